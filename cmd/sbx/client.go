@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+// client talks to the snippetbox JSON API using a personal access token.
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func newClient(cfg *config) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Insecure {
+		// development servers use a self-signed certificate
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &client{
+		server: strings.TrimSuffix(cfg.Server, "/"),
+		token:  cfg.Token,
+		http:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}
+}
+
+// snippetInput is the body of a create request. It matches the JSON form of
+// snippetCreateForm in cmd/web.
+type snippetInput struct {
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	Visibility string `json:"visibility"`
+	Encrypted  bool   `json:"encrypted"`
+	Expires    string `json:"expires"`
+}
+
+func (c *client) create(input snippetInput) (*models.Snippet, error) {
+	var snippet models.Snippet
+	err := c.do(http.MethodPost, "/api/v1/snippets", nil, input, &snippet)
+	return &snippet, err
+}
+
+// get reads a snippet, sending passphrase for those that need one.
+func (c *client) get(slug, passphrase string) (*models.Snippet, error) {
+	header := make(http.Header)
+	if passphrase != "" {
+		header.Set("Snippet-Passphrase", passphrase)
+	}
+
+	var snippet models.Snippet
+	err := c.do(http.MethodGet, "/api/v1/snippets/"+url.PathEscape(slug), header, nil, &snippet)
+	return &snippet, err
+}
+
+func (c *client) mine() ([]*models.Snippet, error) {
+	var snippets []*models.Snippet
+	err := c.do(http.MethodGet, "/api/v1/me/snippets", nil, nil, &snippets)
+	return snippets, err
+}
+
+func (c *client) delete(slug string) error {
+	return c.do(http.MethodDelete, "/api/v1/snippets/"+url.PathEscape(slug), nil, nil, nil)
+}
+
+// viewURL returns the address of a snippet's page in the browser.
+func (c *client) viewURL(slug string) string {
+	return c.server + "/s/" + slug
+}
+
+// do sends body as JSON, along with any extra header, and decodes the "result"
+// of the response into result, unless result is nil. Errors reported by the
+// server are returned as Go errors.
+func (c *client) do(method, path string, header http.Header, body, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, reqBody)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var envelope struct {
+			Error any `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error == nil {
+			return fmt.Errorf("server responded %s", resp.Status)
+		}
+		return apiErrorMessage(envelope.Error)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	envelope := struct {
+		Result any `json:"result"`
+	}{Result: result}
+	return json.NewDecoder(resp.Body).Decode(&envelope)
+}
+
+// apiErrorMessage turns the "error" of an apiError envelope, which is either a
+// message or a map of field errors, into an error.
+func apiErrorMessage(e any) error {
+	fields, ok := e.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%v", e)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, 0, len(keys))
+	for _, key := range keys {
+		messages = append(messages, fmt.Sprintf("%s: %v", key, fields[key]))
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientGetSendsPassphrase(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"With passphrase", "correct horse"},
+		{"Without passphrase", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				w.Write([]byte(`{"result": {"slug": "kD3nR8x_Qa2m", "content": "hello"}}`))
+			}))
+			defer ts.Close()
+
+			c := newClient(&config{Server: ts.URL, Token: "secret"})
+			snippet, err := c.get("kD3nR8x_Qa2m", tt.passphrase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snippet.Content != "hello" {
+				t.Errorf("got content %q; want %q", snippet.Content, "hello")
+			}
+
+			if got := header.Get("Snippet-Passphrase"); got != tt.passphrase {
+				t.Errorf("got Snippet-Passphrase %q; want %q", got, tt.passphrase)
+			}
+			if got := header.Get("Authorization"); got != "Bearer secret" {
+				t.Errorf("got Authorization %q; want %q", got, "Bearer secret")
+			}
+		})
+	}
+}
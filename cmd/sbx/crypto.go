@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ciphertextVersion prefixes snippets encrypted on the client. The format,
+// "v1.<iv>.<data>" with both parts base64url encoded and data being the
+// content sealed with AES-256-GCM, is shared with ui/static/js/main.js so that
+// snippets encrypted by sbx can be read in the browser and the other way
+// round.
+const ciphertextVersion = "v1"
+
+// newKey returns a random key, encoded the way it appears after the "#" in a
+// snippet's link.
+func newKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+func newGCM(encodedKey string) (cipher.AEAD, error) {
+	key, err := base64.RawURLEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("invalid key, it should be the part of the link after #")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encrypt(plaintext, encodedKey string) (string, error) {
+	gcm, err := newGCM(encodedKey)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	data := gcm.Seal(nil, iv, []byte(plaintext), nil)
+
+	return strings.Join([]string{
+		ciphertextVersion,
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(data),
+	}, "."), nil
+}
+
+func decrypt(ciphertext, encodedKey string) (string, error) {
+	parts := strings.Split(ciphertext, ".")
+	if len(parts) != 3 || parts[0] != ciphertextVersion {
+		return "", errors.New("unknown ciphertext format")
+	}
+
+	gcm, err := newGCM(encodedKey)
+	if err != nil {
+		return "", err
+	}
+	iv, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(iv) != gcm.NonceSize() {
+		return "", errors.New("malformed ciphertext")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed ciphertext")
+	}
+
+	plaintext, err := gcm.Open(nil, iv, data, nil)
+	if err != nil {
+		return "", errors.New("decryption failed, the key is wrong")
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+// browserKey and browserCiphertext were produced by encrypt in
+// ui/static/js/main.js, run under Node's WebCrypto, from browserPlaintext.
+const (
+	browserPlaintext  = "échec: build failed\n\ttabs, emoji 🐛 and a trailing newline\n"
+	browserKey        = "EE-n-gsvPsqbOar-zN48rbKOyTHh3A-CxRMeZryqJMA"
+	browserCiphertext = "v1.tRO1pCTmsZFGLRSn.EFBtvcHuwbHsi16hyx54ghlvAal_Nb_suE5GoCuD4ZVlHdWP0iAHfULfjJSWysxpeEAoeyfxuW8psSaRzEcHFAB2_2hprcvIZTwbcJAl"
+)
+
+func TestDecryptBrowserCiphertext(t *testing.T) {
+	plaintext, err := decrypt(browserCiphertext, browserKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != browserPlaintext {
+		t.Errorf("got %q; want %q", plaintext, browserPlaintext)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key, err := newKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := encrypt(browserPlaintext, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := decrypt(ciphertext, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != browserPlaintext {
+		t.Errorf("got %q; want %q", plaintext, browserPlaintext)
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	otherKey, err := newKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		ciphertext string
+		key        string
+	}{
+		{"Wrong key", browserCiphertext, otherKey},
+		{"Malformed key", browserCiphertext, "not-a-key"},
+		{"Unknown version", "v2" + browserCiphertext[2:], browserKey},
+		{"Missing part", "v1.tRO1pCTmsZFGLRSn", browserKey},
+		{"Tampered data", browserCiphertext[:len(browserCiphertext)-2] + "AA", browserKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decrypt(tt.ciphertext, tt.key); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Command sbx is a command line client for snippetbox. It pipes text into
+// new snippets and reads, lists and deletes them through the JSON API.
+//
+//	make test 2>&1 | sbx create -t "build log" -e 7d
+//	sbx get kD3nR8x_Qa2m
+//	sbx get -passphrase "correct horse" kD3nR8x_Qa2m
+//	sbx ls
+//	sbx rm kD3nR8x_Qa2m
+//
+// With -x, create encrypts the content before sending it and prints a link
+// with the key after "#". get decrypts a snippet when given that link, or
+// the slug followed by "#" and the key.
+//
+// The server address and a personal access token, created on the settings
+// page, are read from a config file written by "sbx config".
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `usage:
+  sbx config -server URL -token TOKEN [-insecure]
+  sbx create [-t title] [-e lifetime] [-v visibility] [-x] < file
+  sbx get [-passphrase passphrase] <slug | slug#key | link>
+  sbx ls
+  sbx rm <slug | link>`
+
+type config struct {
+	Server   string `json:"server"`
+	Token    string `json:"token"`
+	Insecure bool   `json:"insecure,omitempty"`
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	err := run(os.Args[1], os.Args[2:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sbx:", err)
+		os.Exit(1)
+	}
+}
+
+func run(command string, args []string) error {
+	if command == "config" {
+		return runConfig(args)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	c := newClient(cfg)
+
+	switch command {
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		title := flags.String("t", "Untitled", "Snippet title")
+		expires := flags.String("e", "365d", "How long to keep the snippet, such as 10m, 3h, 30d, 7 for days, or never")
+		visibility := flags.String("v", "public", "Who can see the snippet: public, unlisted or private")
+		encrypted := flags.Bool("x", false, "Encrypt the content so that the server can't read it")
+		flags.Parse(args)
+
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		content := string(b)
+
+		var key string
+		if *encrypted {
+			if key, err = newKey(); err != nil {
+				return err
+			}
+			if content, err = encrypt(content, key); err != nil {
+				return err
+			}
+		}
+
+		snippet, err := c.create(snippetInput{
+			Title:      *title,
+			Content:    content,
+			Visibility: *visibility,
+			Encrypted:  *encrypted,
+			Expires:    *expires,
+		})
+		if err != nil {
+			return err
+		}
+
+		link := c.viewURL(snippet.Slug)
+		if key != "" {
+			link += "#" + key
+		}
+		fmt.Println(link)
+
+	case "get":
+		flags := flag.NewFlagSet("get", flag.ExitOnError)
+		passphrase := flags.String("passphrase", "", "Passphrase of a snippet protected by one")
+		flags.Parse(args)
+
+		slug, key, err := slugArg(flags.Args())
+		if err != nil {
+			return err
+		}
+
+		snippet, err := c.get(slug, *passphrase)
+		if err != nil {
+			return err
+		}
+
+		content := snippet.Content
+		if snippet.Encrypted {
+			if key == "" {
+				return errors.New("the snippet is encrypted, pass its whole link or slug#key")
+			}
+			if content, err = decrypt(content, key); err != nil {
+				return err
+			}
+		}
+		fmt.Print(content)
+
+	case "ls":
+		snippets, err := c.mine()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SLUG\tTITLE\tVISIBILITY\tCREATED\tEXPIRES")
+		for _, snippet := range snippets {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", snippet.Slug, snippet.Title, snippet.Visibility,
+				snippet.Created.Format("2006-01-02 15:04"), expiresAt(snippet.Expires))
+		}
+		return tw.Flush()
+
+	case "rm":
+		slug, _, err := slugArg(args)
+		if err != nil {
+			return err
+		}
+		return c.delete(slug)
+
+	default:
+		return errors.New(usage)
+	}
+
+	return nil
+}
+
+// expiresAt formats when a snippet expires, where the zero time means it
+// never does.
+func expiresAt(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// slugArg splits the single snippet argument, a slug or a link to a snippet,
+// into the slug and the key of an encrypted snippet, if there is one. The
+// server also accepts the numeric ids older versions of sbx printed.
+func slugArg(args []string) (slug, key string, err error) {
+	if len(args) != 1 {
+		return "", "", errors.New(usage)
+	}
+
+	slug, key, _ = strings.Cut(args[0], "#")
+	if i := strings.LastIndex(slug, "/s/"); i >= 0 {
+		slug = slug[i+len("/s/"):]
+	}
+	if slug == "" || strings.Contains(slug, "/") {
+		return "", "", fmt.Errorf("%q is not a snippet slug or link", args[0])
+	}
+	return slug, key, nil
+}
+
+// configPath returns where the config file lives, normally
+// ~/.config/snippetbox/sbx.json. SBX_CONFIG overrides it.
+func configPath() (string, error) {
+	if path := os.Getenv("SBX_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snippetbox", "sbx.json"), nil
+}
+
+func loadConfig() (*config, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.New(`not configured yet, run "sbx config -server URL -token TOKEN" first`)
+		}
+		return nil, err
+	}
+
+	var cfg config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func runConfig(args []string) error {
+	flags := flag.NewFlagSet("config", flag.ExitOnError)
+	server := flags.String("server", "https://localhost:4000", "Address of the snippetbox server")
+	token := flags.String("token", "", "Personal access token from the settings page")
+	insecure := flags.Bool("insecure", false, "Skip TLS certificate verification, for self-signed development servers")
+	flags.Parse(args)
+
+	if *token == "" {
+		return errors.New("config: -token is required")
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(config{Server: *server, Token: *token, Insecure: *insecure}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// the token grants full access to the account, so keep it private
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
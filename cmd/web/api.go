@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+	"github.com/Yusufdot101/snippetbox/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxAPIBodyBytes caps the size of JSON request bodies.
+const maxAPIBodyBytes = 1 << 20
+
+// snippetList is the result of listing snippets through the API.
+type snippetList struct {
+	Page     int               `json:"page"`
+	PageSize int               `json:"pageSize"`
+	Snippets []*models.Snippet `json:"snippets"`
+}
+
+// apiClientError sends the standard description of statusCode in an apiError
+// envelope.
+func (app *application) apiClientError(w http.ResponseWriter, statusCode int) {
+	WriteJSON(w, statusCode, apiError{Error: http.StatusText(statusCode)})
+}
+
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var v validator.Validator
+
+	page, pageSize := 1, 20
+	if query.Has("page") {
+		var err error
+		page, err = strconv.Atoi(query.Get("page"))
+		v.CheckField(err == nil && page >= 1, "page", "This must be a positive whole number")
+	}
+	if query.Has("page_size") {
+		var err error
+		pageSize, err = strconv.Atoi(query.Get("page_size"))
+		v.CheckField(err == nil && pageSize >= 1 && pageSize <= 100, "page_size", "This must be a whole number between 1 and 100")
+	}
+
+	if !v.Valid() {
+		WriteJSON(w, http.StatusBadRequest, apiError{Error: v.FieldErrors})
+		return
+	}
+
+	snippets, err := app.snippets.Page(pageSize, (page-1)*pageSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// the content of a snippet with a passphrase is only served once it has
+	// been given, through apiSnippetView
+	userID := app.authenticatedUserID(r)
+	for _, snippet := range snippets {
+		if snippet.Protected() && snippet.UserID != userID {
+			snippet.Content = ""
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, apiSuccess{Result: snippetList{
+		Page:     page,
+		PageSize: pageSize,
+		Snippets: snippets,
+	}})
+}
+
+// apiSnippetMine lists the authenticated user's own snippets.
+func (app *application) apiSnippetMine(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, apiSuccess{Result: snippets})
+}
+
+// apiRequestedSnippet is the API counterpart of requestedSnippet, sending
+// errors in an apiError envelope. The ":id" parameter is either a slug or,
+// for older clients, a numeric id.
+func (app *application) apiRequestedSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+	key := params.ByName("id")
+
+	snippet, err := app.snippetBySlug(r, key)
+	if errors.Is(err, models.ErrNoRecord) {
+		if id, convErr := strconv.Atoi(key); convErr == nil && id > 0 {
+			snippet, err = app.snippetByID(r, id)
+		}
+	}
+	if err != nil {
+		app.apiSnippetError(w, err)
+		return nil, false
+	}
+
+	if !app.unlocked(r, snippet) && !app.apiUnlock(w, r, snippet) {
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// apiReadSnippet is the API counterpart of readSnippet.
+func (app *application) apiReadSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	snippet, ok = app.apiRequestedSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	snippet, err := app.consume(r, snippet)
+	if err != nil {
+		app.apiSnippetError(w, err)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// apiSnippetError is the API counterpart of snippetError.
+func (app *application) apiSnippetError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrNoRecord) {
+		app.apiClientError(w, http.StatusNotFound)
+	} else if errors.Is(err, models.ErrBurned) {
+		WriteJSON(w, http.StatusGone, apiError{Error: "this snippet has been burned"})
+	} else {
+		app.serverError(w, err)
+	}
+}
+
+// apiUnlock checks the passphrase sent in the Snippet-Passphrase header for
+// a protected snippet. Wrong passphrases count towards the same per snippet
+// limit as the unlock form. If the passphrase isn't right, the error response
+// has already been sent and ok is false.
+func (app *application) apiUnlock(w http.ResponseWriter, r *http.Request, snippet *models.Snippet) (ok bool) {
+	passphrase := r.Header.Get("Snippet-Passphrase")
+	if passphrase == "" {
+		WriteJSON(w, http.StatusForbidden, apiError{Error: "this snippet needs a passphrase, send it in the Snippet-Passphrase header"})
+		return false
+	}
+
+	attempt, ok := app.unlockLimiter.Reserve(snippet.Slug)
+	if !ok {
+		app.apiClientError(w, http.StatusTooManyRequests)
+		return false
+	}
+
+	if !snippet.MatchesPassphrase(passphrase) {
+		WriteJSON(w, http.StatusForbidden, apiError{Error: "incorrect passphrase"})
+		return false
+	}
+	app.unlockLimiter.Refund(snippet.Slug, attempt)
+
+	return true
+}
+
+func (app *application) apiSnippetView(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiReadSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, apiSuccess{Result: snippet})
+}
+
+// apiSnippetRaw serves just the content of a snippet. For encrypted snippets
+// that is the ciphertext, exactly as the browser produced it, for clients
+// that decrypt it themselves.
+func (app *application) apiSnippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiReadSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", snippetContentType(snippet))
+	io.WriteString(w, snippet.Content)
+}
+
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	form := snippetCreateForm{
+		Visibility: models.VisibilityPublic,
+		Expires:    lifetime(app.expiryPolicy.Default()),
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&form); err != nil {
+		WriteJSON(w, http.StatusBadRequest, apiError{Error: "request body must be a JSON object with title, content, language, visibility, encrypted, burnAfterReading, passphrase and expires"})
+		return
+	}
+
+	form.validate(app.expiryPolicy)
+
+	if !form.Valid() {
+		WriteJSON(w, http.StatusUnprocessableEntity, apiError{Error: form.FieldErrors})
+		return
+	}
+
+	snippet, err := form.snippet(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	slug, err := app.snippets.Insert(snippet, form.expiresAt)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	snippet, err = app.snippets.GetBySlug(slug)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/snippets/"+slug)
+	WriteJSON(w, http.StatusCreated, apiSuccess{Result: snippet})
+}
+
+// apiSnippetExtend gives one of the user's snippets a new lifetime, from a
+// JSON body such as {"expires": "30d"}, and returns the updated snippet.
+func (app *application) apiSnippetExtend(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiRequestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.apiClientError(w, http.StatusForbidden)
+		return
+	}
+
+	var form snippetExtendForm
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&form); err != nil {
+		WriteJSON(w, http.StatusBadRequest, apiError{Error: "request body must be a JSON object with expires"})
+		return
+	}
+
+	form.validate(app.expiryPolicy, snippet)
+
+	if !form.Valid() {
+		WriteJSON(w, http.StatusUnprocessableEntity, apiError{Error: form.FieldErrors})
+		return
+	}
+
+	err := app.snippets.Extend(snippet.ID, form.expiresAt)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiClientError(w, http.StatusNotFound)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	snippet.Expires = form.expiresAt
+	WriteJSON(w, http.StatusOK, apiSuccess{Result: snippet})
+}
+
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiRequestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.apiClientError(w, http.StatusForbidden)
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiClientError(w, http.StatusNotFound)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, apiSuccess{Result: "snippet deleted"})
+}
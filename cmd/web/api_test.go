@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+// decodeResult decodes the result of a successful API response into result.
+func decodeResult(t *testing.T, body string, result any) {
+	t.Helper()
+	envelope := struct {
+		Result any `json:"result"`
+	}{Result: result}
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+}
+
+func TestAPIListLeavesOutProtectedContent(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+
+	protected := &models.Snippet{UserID: aliceID, Title: "Locked", Content: "behind a passphrase"}
+	if err := protected.SetPassphrase("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	insertSnippet(t, app, protected)
+	insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Open", Content: "for everyone"})
+
+	code, _, body := ts.newBrowser(t).get(t, "/api/v1/snippets")
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	var list snippetList
+	decodeResult(t, body, &list)
+
+	contents := make(map[string]string)
+	for _, snippet := range list.Snippets {
+		contents[snippet.Title] = snippet.Content
+	}
+	if len(contents) != 2 {
+		t.Fatalf("got %d snippets; want 2", len(contents))
+	}
+	if contents["Locked"] != "" {
+		t.Errorf("protected snippet listed with its content %q", contents["Locked"])
+	}
+	if contents["Open"] != "for everyone" {
+		t.Errorf("unprotected snippet listed with content %q; want %q", contents["Open"], "for everyone")
+	}
+}
+
+func TestAPISnippetLifecycle(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	bobID := insertUser(t, app, "Bob", "bob@example.com")
+
+	alice := ts.newBrowser(t)
+	alice.token = insertToken(t, app, aliceID)
+	bob := ts.newBrowser(t)
+	bob.token = insertToken(t, app, bobID)
+	anonymous := ts.newBrowser(t)
+
+	input := map[string]any{
+		"title":    "Made by a script",
+		"content":  "echo hello",
+		"language": "bash",
+		"expires":  "1d",
+	}
+
+	code, header, body := alice.postJSON(t, "/api/v1/snippets", input)
+	if code != http.StatusCreated {
+		t.Fatalf("create: got status %d; want %d: %s", code, http.StatusCreated, body)
+	}
+	var created models.Snippet
+	decodeResult(t, body, &created)
+	if created.UserID != aliceID || created.Title != "Made by a script" || created.Language != "bash" {
+		t.Errorf("create: got %+v", created)
+	}
+	if want := "/api/v1/snippets/" + created.Slug; header.Get("Location") != want {
+		t.Errorf("create: got Location %q; want %q", header.Get("Location"), want)
+	}
+
+	code, _, body = anonymous.get(t, "/api/v1/snippets/"+created.Slug)
+	if code != http.StatusOK {
+		t.Fatalf("get: got status %d; want %d", code, http.StatusOK)
+	}
+	var got models.Snippet
+	decodeResult(t, body, &got)
+	if got.ID != created.ID || got.Content != "echo hello" {
+		t.Errorf("get: got %+v; want the created snippet", got)
+	}
+
+	code, _, body = anonymous.get(t, "/api/v1/snippets")
+	if code != http.StatusOK {
+		t.Fatalf("list: got status %d; want %d", code, http.StatusOK)
+	}
+	var list snippetList
+	decodeResult(t, body, &list)
+	if len(list.Snippets) != 1 || list.Snippets[0].Slug != created.Slug {
+		t.Errorf("list: got %d snippets; want only the created one", len(list.Snippets))
+	}
+
+	code, _, body = alice.get(t, "/api/v1/me/snippets")
+	if code != http.StatusOK {
+		t.Fatalf("list own: got status %d; want %d", code, http.StatusOK)
+	}
+	var mine []*models.Snippet
+	decodeResult(t, body, &mine)
+	if len(mine) != 1 || mine[0].Slug != created.Slug {
+		t.Errorf("list own: got %d snippets; want only the created one", len(mine))
+	}
+
+	code, _, _ = bob.do(t, http.MethodDelete, "/api/v1/snippets/"+created.Slug, nil)
+	if code != http.StatusForbidden {
+		t.Errorf("delete by another user: got status %d; want %d", code, http.StatusForbidden)
+	}
+
+	code, _, _ = alice.do(t, http.MethodDelete, "/api/v1/snippets/"+created.Slug, nil)
+	if code != http.StatusOK {
+		t.Fatalf("delete: got status %d; want %d", code, http.StatusOK)
+	}
+
+	code, _, _ = anonymous.get(t, "/api/v1/snippets/"+created.Slug)
+	if code != http.StatusNotFound {
+		t.Errorf("get after delete: got status %d; want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	token := insertToken(t, app, aliceID)
+	input := map[string]any{"title": "Hello", "content": "world", "language": "text"}
+
+	t.Run("Unknown token", func(t *testing.T) {
+		b := ts.newBrowser(t)
+		b.token = "not-a-token"
+		code, _, _ := b.get(t, "/api/v1/snippets")
+		if code != http.StatusUnauthorized {
+			t.Errorf("got status %d; want %d", code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("Session without CSRF token", func(t *testing.T) {
+		b := ts.newBrowser(t)
+		b.login(t, "alice@example.com")
+		code, _, _ := b.postJSON(t, "/api/v1/snippets", input)
+		if code != http.StatusBadRequest {
+			t.Errorf("got status %d; want %d", code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Token without CSRF token", func(t *testing.T) {
+		b := ts.newBrowser(t)
+		b.token = token
+		code, _, _ := b.postJSON(t, "/api/v1/snippets", input)
+		if code != http.StatusCreated {
+			t.Errorf("got status %d; want %d", code, http.StatusCreated)
+		}
+
+		tokens, err := app.tokens.ByUser(aliceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tokens) != 1 || tokens[0].LastUsed.IsZero() {
+			t.Errorf("token last used time not recorded")
+		}
+	})
+
+	t.Run("Revoked token", func(t *testing.T) {
+		tokens, err := app.tokens.ByUser(aliceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings := ts.newBrowser(t)
+		settings.login(t, "alice@example.com")
+		code, _, _ := settings.postForm(t, fmt.Sprintf("/users/settings/tokens/%d/revoke", tokens[0].ID), url.Values{})
+		if code != http.StatusSeeOther {
+			t.Fatalf("revoking: got status %d; want %d", code, http.StatusSeeOther)
+		}
+
+		b := ts.newBrowser(t)
+		b.token = token
+		code, _, _ = b.get(t, "/api/v1/me/snippets")
+		if code != http.StatusUnauthorized {
+			t.Errorf("got status %d; want %d", code, http.StatusUnauthorized)
+		}
+	})
+}
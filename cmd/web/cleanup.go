@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// cleanupExpired removes expired snippets every interval, batchSize at a
+// time, until ctx is done. With archive set the snippets are archived rather
+// than lost. A run that is under way when ctx is done stops after its
+// current batch.
+func (app *application) cleanupExpired(ctx context.Context, interval time.Duration, batchSize int, archive bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.removeExpired(ctx, batchSize, archive)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// removeExpired removes batches of expired snippets until there are none
+// left or ctx is done, and logs how many it removed.
+func (app *application) removeExpired(ctx context.Context, batchSize int, archive bool) {
+	total := 0
+	for ctx.Err() == nil {
+		n, err := app.snippets.DeleteExpired(batchSize, archive)
+		if err != nil {
+			app.errorLog.Printf("removing expired snippets: %v", err)
+			break
+		}
+		total += n
+		if n < batchSize {
+			break
+		}
+	}
+
+	if total == 0 {
+		return
+	}
+	if archive {
+		app.infoLog.Printf("Archived %d expired snippet(s)", total)
+	} else {
+		app.infoLog.Printf("Deleted %d expired snippet(s)", total)
+	}
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"crypto/hmac"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Yusufdot101/snippetbox/internal/diff"
+	"github.com/Yusufdot101/snippetbox/internal/expiry"
+	"github.com/Yusufdot101/snippetbox/internal/highlight"
+	"github.com/Yusufdot101/snippetbox/internal/markdown"
 	"github.com/Yusufdot101/snippetbox/internal/models"
+	"github.com/Yusufdot101/snippetbox/internal/search"
 	"github.com/Yusufdot101/snippetbox/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
@@ -28,20 +38,434 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	app.render(w, http.StatusOK, page, data)
 }
 
+// searchPageSize is how many results are shown per page of a search.
+const searchPageSize = 10
+
+// excerptSize is about how many bytes of a snippet's content are quoted in
+// search results.
+const excerptSize = 240
+
+// searchResult is a snippet found by a search, with the words searched for
+// marked in its title and in an excerpt of its content.
+type searchResult struct {
+	Snippet *models.Snippet
+	Title   []search.Fragment
+	Excerpt []search.Fragment
+}
+
+// searchPage is a page of search results. PrevPage and NextPage are 0 when
+// there is no such page.
+type searchPage struct {
+	Query    string
+	Results  []searchResult
+	Total    int
+	Page     int
+	PrevPage int
+	NextPage int
+}
+
+func (app *application) snippetSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	userID := app.authenticatedUserID(r)
+
+	page := 1
+	if r.URL.Query().Has("page") {
+		var err error
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	data := app.newTemplateData(r)
+	data.Search = &searchPage{Query: query, Page: page}
+
+	if query != "" {
+		results, err := app.searchIndex.Search(search.Query{
+			Text:   query,
+			UserID: userID,
+			Offset: (page - 1) * searchPageSize,
+			Limit:  searchPageSize,
+		})
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		for _, hit := range results.Hits {
+			snippet, err := app.snippets.Get(hit.ID)
+			if err != nil {
+				if errors.Is(err, models.ErrNoRecord) {
+					// the snippet expired or was deleted after the search
+					continue
+				}
+				app.serverError(w, err)
+				return
+			}
+			// the index should only return snippets the user may find, but
+			// it is cheap to make sure
+			if !snippet.VisibleTo(userID) {
+				continue
+			}
+			data.Search.Results = append(data.Search.Results, searchResult{
+				Snippet: snippet,
+				Title:   search.Highlight(snippet.Title, query),
+				Excerpt: search.Excerpt(searchableContent(snippet), query, excerptSize),
+			})
+		}
+
+		data.Search.Total = results.Total
+		if page > 1 {
+			data.Search.PrevPage = page - 1
+		}
+		if page*searchPageSize < results.Total {
+			data.Search.NextPage = page + 1
+		}
+	}
+
+	app.render(w, http.StatusOK, "search.tmpl.html", data)
+}
+
 func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// Markdown snippets are shown rendered unless the source is asked for.
+	// The server can't read encrypted ones, so main.js shows those as their
+	// decrypted source, without rendering them.
+	if snippet.Language == "markdown" && !snippet.Encrypted {
+		data.ShowSource = r.URL.Query().Get("source") != ""
+		if !data.ShowSource {
+			rendered, err := markdown.HTML(snippet.Content)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			data.Markdown = rendered
+		}
+	}
+
+	page := "view.tmpl.html"
+	app.render(w, http.StatusOK, page, data)
+}
+
+// snippetRaw serves the content of a snippet as plain text, for piping into
+// a shell with curl. Encrypted snippets are served as their ciphertext, which
+// isn't text.
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", snippetContentType(snippet))
+	io.WriteString(w, snippet.Content)
+}
+
+// snippetDownload serves the content of a snippet as a file named after its
+// title.
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{
+		"filename": snippetFilename(snippet),
+	})
+
+	w.Header().Set("Content-Type", snippetContentType(snippet))
+	w.Header().Set("Content-Disposition", disposition)
+	io.WriteString(w, snippet.Content)
+}
+
+// requestedSnippet loads the snippet named by the ":slug" parameter, asking
+// for its passphrase if it has one. It never burns the snippet: handlers that
+// show its content use readSnippet instead. If it can't, the appropriate
+// error response has already been sent and ok is false.
+func (app *application) requestedSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
 	// retrieve a slice containing the paramaters in the url
 	params := httprouter.ParamsFromContext(r.Context())
 
-	// get the value of "id" parameter
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil || id < 1 {
-		app.clientError(w, 400)
+	snippet, err := app.snippetBySlug(r, params.ByName("slug"))
+	if err != nil {
+		app.snippetError(w, r, err)
+		return nil, false
+	}
+
+	if !app.unlocked(r, snippet) {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = unlockForm{}
+		app.render(w, http.StatusForbidden, "unlock.tmpl.html", data)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// readSnippet is requestedSnippet for handlers that show the snippet's
+// content, burning it if it is to be deleted after reading.
+func (app *application) readSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	snippet, ok = app.requestedSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	snippet, err := app.consume(r, snippet)
+	if err != nil {
+		app.snippetError(w, r, err)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// revisedSnippet is requestedSnippet for the history and diff pages. Those
+// show a snippet's content without burning it, so a burn after reading
+// snippet's revisions are only shown to its author.
+func (app *application) revisedSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	snippet, ok = app.requestedSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if snippet.BurnAfterReading && snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// snippetError sends the response for an error loading a snippet.
+func (app *application) snippetError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, models.ErrNoRecord) {
+		app.clientError(w, 404)
+	} else if errors.Is(err, models.ErrBurned) {
+		data := app.newTemplateData(r)
+		app.render(w, http.StatusGone, "burned.tmpl.html", data)
+	} else {
+		app.serverError(w, err)
+	}
+}
+
+// snippetBySlug returns the snippet with the given slug if the user may see
+// it. Private snippets of other users are treated as if they didn't exist.
+func (app *application) snippetBySlug(r *http.Request, slug string) (*models.Snippet, error) {
+	snippet, err := app.snippets.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		return nil, models.ErrNoRecord
+	}
+	return snippet, nil
+}
+
+// unlockedKey is the session key recording that the passphrase of the snippet
+// with the given slug has been entered.
+func unlockedKey(slug string) string {
+	return "unlocked:" + slug
+}
+
+// unlocked reports whether the user may read snippet as far as its
+// passphrase is concerned: it has none, they wrote it, or they have entered
+// it earlier in this session.
+func (app *application) unlocked(r *http.Request, snippet *models.Snippet) bool {
+	return !snippet.Protected() || snippet.UserID == app.authenticatedUserID(r) ||
+		app.sessionManager.GetBool(r.Context(), unlockedKey(snippet.Slug))
+}
+
+type unlockForm struct {
+	Passphrase          string `form:"passphrase"`
+	validator.Validator `form:"-"`
+}
+
+// snippetUnlockPost checks the passphrase of a protected snippet and, if it is
+// right, lets the user read the snippet for the rest of their session. Wrong
+// passphrases are counted per snippet, so guessing is slow however many
+// sessions it is spread over.
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	snippet, err := app.snippetBySlug(r, params.ByName("slug"))
+	if err != nil {
+		app.snippetError(w, r, err)
+		return
+	}
+
+	if app.unlocked(r, snippet) {
+		http.Redirect(w, r, "/s/"+snippet.Slug, http.StatusSeeOther)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var form unlockForm
+
+	err = app.formDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	page := "unlock.tmpl.html"
+
+	attempt, ok := app.unlockLimiter.Reserve(snippet.Slug)
+	if !ok {
+		form.AddNonFieldError("Too many wrong passphrases. Try again later.")
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, page, data)
+		return
+	}
+
+	if !snippet.MatchesPassphrase(form.Passphrase) {
+		form.AddFieldError("passphrase", "Incorrect passphrase")
+		data.Form = form
+		app.render(w, http.StatusForbidden, page, data)
 		return
 	}
+	app.unlockLimiter.Refund(snippet.Slug, attempt)
+
+	app.sessionManager.Put(r.Context(), unlockedKey(snippet.Slug), true)
+
+	http.Redirect(w, r, "/s/"+snippet.Slug, http.StatusSeeOther)
+}
+
+// consume burns a burn after reading snippet when someone other than its
+// author reads it, returning the copy that was read in the same transaction
+// as it was deleted. Other snippets are returned as they are.
+func (app *application) consume(r *http.Request, snippet *models.Snippet) (*models.Snippet, error) {
+	if !snippet.BurnAfterReading || snippet.UserID == app.authenticatedUserID(r) {
+		return snippet, nil
+	}
+	return app.snippets.Burn(snippet.Slug)
+}
+
+// snippetByID returns the snippet with the given numeric id, for links made
+// before snippets had slugs. Ids are sequential, so only public snippets and
+// the user's own can be found this way; counting through them mustn't reveal
+// unlisted snippets.
+func (app *application) snippetByID(r *http.Request, id int) (*models.Snippet, error) {
 	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if snippet.Visibility != models.VisibilityPublic && snippet.UserID != app.authenticatedUserID(r) {
+		return nil, models.ErrNoRecord
+	}
+	return snippet, nil
+}
+
+// snippetRedirect returns a handler that sends an old "/snippets/...:id" link
+// on to the slug based route with the given suffix, keeping the query string.
+func (app *application) snippetRedirect(suffix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+
+		id, err := strconv.Atoi(params.ByName("id"))
+		if err != nil || id < 1 {
+			app.clientError(w, 400)
+			return
+		}
+
+		snippet, err := app.snippetByID(r, id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.clientError(w, 404)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		target := "/s/" + snippet.Slug + suffix
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}
+}
+
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.revisedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.snippets.History(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Snippets = revisions
+
+	page := "history.tmpl.html"
+	app.render(w, http.StatusOK, page, data)
+}
+
+// snippetDiff shows what changed between the "from" and "to" revisions of a
+// snippet. By default it compares the latest revision with the one before it.
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.revisedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	to := snippet.Revision
+	if query.Has("to") {
+		var err error
+		to, err = strconv.Atoi(query.Get("to"))
+		if err != nil || to < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	from := max(to-1, 1)
+	if query.Has("from") {
+		var err error
+		from, err = strconv.Atoi(query.Get("from"))
+		if err != nil || from < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	fromRevision, err := app.snippets.Revision(snippet.ID, from)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.clientError(w, http.StatusNotFound)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	toRevision, err := app.snippets.Revision(snippet.ID, to)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
-			app.clientError(w, 404)
+			app.clientError(w, http.StatusNotFound)
 		} else {
 			app.serverError(w, err)
 		}
@@ -50,8 +474,11 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
+	data.DiffFrom = fromRevision
+	data.DiffTo = toRevision
+	data.Diff = diff.Unified(fromRevision.Content, toRevision.Content, 3)
 
-	page := "view.tmpl.html"
+	page := "diff.tmpl.html"
 	app.render(w, http.StatusOK, page, data)
 }
 
@@ -59,18 +486,104 @@ func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 
 	data.Form = snippetCreateForm{
-		Expires: 365,
+		Visibility: models.VisibilityPublic,
+		Expires:    lifetime(app.expiryPolicy.Default()),
 	}
 
 	page := "create.tmpl.html"
 	app.render(w, http.StatusOK, page, data)
 }
 
+// snippetCreateForm is filled in from the HTML form or, through the API,
+// from a JSON request body.
 type snippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
-	validator.Validator `form:"-"`
+	Title               string            `form:"title" json:"title"`
+	Content             string            `form:"content" json:"content"`
+	Language            string            `form:"language" json:"language"`
+	Visibility          models.Visibility `form:"visibility" json:"visibility"`
+	Encrypted           bool              `form:"encrypted" json:"encrypted"`
+	BurnAfterReading    bool              `form:"burn" json:"burnAfterReading"`
+	Passphrase          string            `form:"passphrase" json:"passphrase"`
+	RemovePassphrase    bool              `form:"remove_passphrase" json:"-"`
+	Expires             lifetime          `form:"expires" json:"expires"`
+	ExpiresCustom       string            `form:"expires_custom" json:"-"`
+	validator.Validator `form:"-" json:"-"`
+
+	// expiresAt is when the snippet expires, as worked out by validate.
+	expiresAt time.Time
+}
+
+// lifetime is how long a snippet should be kept, in a form expiry.Parse
+// reads, or "custom" on the HTML form to use the value typed in next to it.
+// Early versions of the API took a number of days, which is still accepted.
+type lifetime string
+
+func (l *lifetime) UnmarshalJSON(b []byte) error {
+	var days int
+	if err := json.Unmarshal(b, &days); err == nil {
+		*l = lifetime(strconv.Itoa(days) + "d")
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*l = lifetime(s)
+	return nil
+}
+
+// ciphertextRX matches the content of a snippet encrypted by main.js:
+// "v1.<iv>.<data>", with both parts base64url encoded.
+var ciphertextRX = regexp.MustCompile(`^v1\.[A-Za-z0-9_-]{16}\.[A-Za-z0-9_-]+$`)
+
+// validate runs the checks shared by the create and edit snippet forms,
+// allowing the lifetimes policy does.
+func (form *snippetCreateForm) validate(policy expiry.Policy) {
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(!form.Encrypted || validator.Matches(form.Content, ciphertextRX), "content", "This must be content encrypted by the browser")
+	form.CheckField(form.Language == "" || validator.PermittedValue(form.Language, highlight.Values()...), "language", "This language is not supported")
+	form.CheckField(len(form.Passphrase) <= 72, "passphrase", "This cannot be more than 72 bytes long")
+	form.CheckField(validator.PermittedValue(string(form.Visibility), "public", "unlisted", "private"), "visibility", "This field must be public, unlisted or private")
+	form.expiresAt = checkLifetime(&form.Validator, policy, form.Expires, form.ExpiresCustom)
+}
+
+// checkLifetime checks the lifetime picked on a form, with custom being the
+// value typed in next to the presets, against policy and returns when it
+// runs out counting from now. Problems are recorded on v under "expires".
+func checkLifetime(v *validator.Validator, policy expiry.Policy, picked lifetime, custom string) time.Time {
+	value := string(picked)
+	if value == "custom" {
+		value = custom
+	}
+	expiresAt, err := policy.Expires(value, time.Now())
+	v.CheckField(err == nil, "expires", "This must be a lifetime such as 3h or 30d, "+policy.String())
+	return expiresAt
+}
+
+// snippet returns the snippet described by the form, written by userID. A
+// blank language is detected from the content, unless it is encrypted, and
+// the passphrase, if any, is hashed.
+func (form *snippetCreateForm) snippet(userID int) (*models.Snippet, error) {
+	language := form.Language
+	if language == "" && !form.Encrypted {
+		language = highlight.Detect(form.Content)
+	}
+	snippet := &models.Snippet{
+		UserID:           userID,
+		Title:            form.Title,
+		Content:          form.Content,
+		Language:         language,
+		Visibility:       form.Visibility,
+		Encrypted:        form.Encrypted,
+		BurnAfterReading: form.BurnAfterReading,
+	}
+	if err := snippet.SetPassphrase(form.Passphrase); err != nil {
+		return nil, err
+	}
+	return snippet, nil
 }
 
 func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
@@ -94,11 +607,7 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	permittedExpiresValues := []string{"1", "7", "365"}
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PremittedInt(form.Expires, permittedExpiresValues...), "expires", "This field must be in ["+strings.Join(permittedExpiresValues, ", ")+"]")
+	form.validate(app.expiryPolicy)
 
 	if !form.Valid() {
 		data := app.newTemplateData(r)
@@ -108,7 +617,13 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	snippet, err := form.snippet(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	slug, err := app.snippets.Insert(snippet, form.expiresAt)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -116,7 +631,332 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 
 	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully!")
 
-	http.Redirect(w, r, fmt.Sprintf("/snippets/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, "/s/"+slug, http.StatusSeeOther)
+}
+
+// ownedSnippet loads the snippet named by the ":slug" parameter and makes sure it
+// belongs to the logged in user. If it doesn't, the appropriate error response
+// has already been sent and ok is false.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (snippet *models.Snippet, ok bool) {
+	snippet, ok = app.requestedSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	form := snippetCreateForm{
+		Title:            snippet.Title,
+		Content:          snippet.Content,
+		Language:         snippet.Language,
+		Visibility:       snippet.Visibility,
+		Encrypted:        snippet.Encrypted,
+		BurnAfterReading: snippet.BurnAfterReading,
+		Expires:          lifetime(app.expiryPolicy.Default()),
+	}
+	// a snippet kept forever stays that way unless the author changes it
+	if snippet.Expires.IsZero() && app.expiryPolicy.AllowNever {
+		form.Expires = expiry.Never
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = form
+
+	page := "edit.tmpl.html"
+	app.render(w, http.StatusOK, page, data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var form snippetCreateForm
+
+	err = app.formDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate(app.expiryPolicy)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		page := "edit.tmpl.html"
+		app.render(w, http.StatusBadRequest, page, data)
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+	updated, err := form.snippet(userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	updated.ID = snippet.ID
+	// a blank passphrase keeps the current one unless asked to remove it
+	if form.Passphrase == "" && !form.RemovePassphrase {
+		updated.PassphraseHash = snippet.PassphraseHash
+	}
+
+	err = app.snippets.Update(updated, userID, form.expiresAt)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet updated successfully!")
+
+	http.Redirect(w, r, "/s/"+snippet.Slug, http.StatusSeeOther)
+}
+
+// snippetExtendForm gives a snippet a new lifetime, counted from now, through
+// the HTML form or the API.
+type snippetExtendForm struct {
+	Expires             lifetime `form:"expires" json:"expires"`
+	ExpiresCustom       string   `form:"expires_custom" json:"-"`
+	validator.Validator `form:"-" json:"-"`
+
+	// expiresAt is when the snippet expires, as worked out by validate.
+	expiresAt time.Time
+}
+
+// validate checks the new lifetime of snippet against policy. It has to
+// leave the snippet around for longer than it would have been otherwise.
+func (form *snippetExtendForm) validate(policy expiry.Policy, snippet *models.Snippet) {
+	form.CheckField(!snippet.Expires.IsZero(), "expires", "This snippet never expires")
+	form.expiresAt = checkLifetime(&form.Validator, policy, form.Expires, form.ExpiresCustom)
+	if form.Valid() && !form.expiresAt.IsZero() {
+		form.CheckField(form.expiresAt.After(snippet.Expires), "expires", "This snippet already expires later than that")
+	}
+}
+
+func (app *application) snippetExtend(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	form := snippetExtendForm{Expires: lifetime(app.expiryPolicy.Default())}
+	// links pick the lifetime, so that extending takes one click
+	if value := r.URL.Query().Get("expires"); value != "" && app.expiryPolicy.Check(value) == nil {
+		form.Expires, form.ExpiresCustom = "custom", value
+		for _, preset := range app.expiryPolicy.Presets() {
+			if preset.Value == value {
+				form.Expires, form.ExpiresCustom = lifetime(value), ""
+			}
+		}
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = form
+
+	page := "extend.tmpl.html"
+	app.render(w, http.StatusOK, page, data)
+}
+
+func (app *application) snippetExtendPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var form snippetExtendForm
+
+	err = app.formDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate(app.expiryPolicy, snippet)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		page := "extend.tmpl.html"
+		app.render(w, http.StatusBadRequest, page, data)
+		return
+	}
+
+	err = app.snippets.Extend(snippet.ID, form.expiresAt)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.clientError(w, http.StatusNotFound)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet extended successfully!")
+
+	http.Redirect(w, r, "/s/"+snippet.Slug, http.StatusSeeOther)
+}
+
+// snippetExtendLink extends a snippet from the link in a reminder email,
+// without the owner having to log in. The signature in the link, which only
+// the server can make, stands in for both the session and the CSRF token. As
+// it covers the snippet's expiry, a link works once: a mail scanner following
+// it first only does what the reminder offered, and clicking it afterwards
+// leads to the extend form.
+func (app *application) snippetExtendLink(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	snippet, err := app.snippets.GetBySlug(params.ByName("slug"))
+	if err != nil {
+		app.snippetError(w, r, err)
+		return
+	}
+
+	value := r.URL.Query().Get("expires")
+	form := snippetExtendForm{Expires: "custom", ExpiresCustom: value}
+	form.validate(app.expiryPolicy, snippet)
+
+	signature := app.extendSignature(snippet, value)
+	if !hmac.Equal([]byte(params.ByName("signature")), []byte(signature)) || !form.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "That link has already been used or is no longer valid.")
+		http.Redirect(w, r, "/s/"+snippet.Slug+"/extend", http.StatusSeeOther)
+		return
+	}
+
+	err = app.snippets.Extend(snippet.ID, form.expiresAt)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.clientError(w, http.StatusNotFound)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet extended successfully!")
+
+	// someone else following the link may not be allowed to see the snippet
+	if snippet.UserID == app.authenticatedUserID(r) {
+		http.Redirect(w, r, "/s/"+snippet.Slug, http.StatusSeeOther)
+	} else {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func (app *application) snippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	page := "delete.tmpl.html"
+	app.render(w, http.StatusOK, page, data)
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.clientError(w, http.StatusNotFound)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet deleted successfully!")
+
+	http.Redirect(w, r, "/snippets/mine", http.StatusSeeOther)
+}
+
+// reminder is a notification along with the snippet it is about.
+type reminder struct {
+	*models.Notification
+	Snippet *models.Snippet
+}
+
+// notificationList shows the user's snippets that are about to expire and
+// marks the notifications about them as seen.
+func (app *application) notificationList(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+
+	notifications, err := app.notifications.ByUser(userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	reminders := make([]reminder, 0, len(notifications))
+	for _, notification := range notifications {
+		snippet, err := app.snippets.Get(notification.SnippetID)
+		if err != nil {
+			// the snippet expired after the notification was read
+			continue
+		}
+		reminders = append(reminders, reminder{Notification: notification, Snippet: snippet})
+	}
+
+	data := app.newTemplateData(r)
+	data.Reminders = reminders
+
+	page := "notifications.tmpl.html"
+	app.render(w, http.StatusOK, page, data)
+
+	if err := app.notifications.MarkSeen(userID); err != nil {
+		app.errorLog.Print(err)
+	}
+}
+
+func (app *application) snippetMine(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+
+	snippets, err := app.snippets.ByUser(userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+
+	page := "mine.tmpl.html"
+	app.render(w, http.StatusOK, page, data)
 }
 
 type userCreateForm struct {
@@ -285,6 +1125,100 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+type tokenCreateForm struct {
+	Name                string `form:"name"`
+	Expires             int    `form:"expires"`
+	validator.Validator `form:"-"`
+}
+
+// userSettings lists the user's personal access tokens along with a form for
+// creating another.
+func (app *application) userSettings(w http.ResponseWriter, r *http.Request) {
+	app.renderSettings(w, r, http.StatusOK, tokenCreateForm{Expires: 90}, "")
+}
+
+// renderSettings renders the settings page. newToken is the plaintext of a
+// token that was just created, which is the only time it can be shown.
+func (app *application) renderSettings(w http.ResponseWriter, r *http.Request, status int, form tokenCreateForm, newToken string) {
+	tokens, err := app.tokens.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.Tokens = tokens
+	data.NewToken = newToken
+
+	page := "settings.tmpl.html"
+	app.render(w, status, page, data)
+}
+
+func (app *application) tokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var form tokenCreateForm
+
+	err = app.formDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	permittedExpiresValues := []string{"0", "30", "90", "365"}
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This cannot be more than 100 characters long")
+	form.CheckField(validator.PermittedValue(strconv.Itoa(form.Expires), permittedExpiresValues...), "expires", "This field must be in ["+strings.Join(permittedExpiresValues, ", ")+"]")
+
+	if !form.Valid() {
+		app.renderSettings(w, r, http.StatusBadRequest, form, "")
+		return
+	}
+
+	var expires time.Time
+	if form.Expires > 0 {
+		expires = time.Now().UTC().AddDate(0, 0, form.Expires)
+	}
+
+	token, err := app.tokens.Insert(app.authenticatedUserID(r), form.Name, expires)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// the token is rendered straight away instead of redirecting, so that
+	// its plaintext never has to be stored, not even in the session
+	app.renderSettings(w, r, http.StatusOK, tokenCreateForm{Expires: 90}, token)
+}
+
+func (app *application) tokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.tokens.Delete(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.clientError(w, http.StatusNotFound)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Token revoked successfully!")
+	http.Redirect(w, r, "/users/settings", http.StatusSeeOther)
+}
+
 type apiError struct {
 	Error any `json:"error"`
 }
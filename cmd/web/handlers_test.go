@@ -0,0 +1,529 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+// snippetForm returns the fields of the snippet form, with those in changes
+// replacing the defaults.
+func snippetForm(changes url.Values) url.Values {
+	form := url.Values{
+		"title":      {"Hello"},
+		"content":    {"Hello, world"},
+		"language":   {"text"},
+		"visibility": {"public"},
+		"expires":    {"7d"},
+	}
+	for name, values := range changes {
+		form[name] = values
+	}
+	return form
+}
+
+func TestSnippetCreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	insertUser(t, app, "Alice", "alice@example.com")
+	alice := ts.newBrowser(t)
+	alice.login(t, "alice@example.com")
+
+	tests := []struct {
+		name         string
+		browser      *browser
+		changes      url.Values
+		wantCode     int
+		wantLocation string
+	}{
+		{"valid", alice, nil, http.StatusSeeOther, "/s/"},
+		{"days without a unit", alice, url.Values{"expires": {"custom"}, "expires_custom": {"7"}}, http.StatusSeeOther, "/s/"},
+		{"blank title", alice, url.Values{"title": {""}}, http.StatusBadRequest, ""},
+		{"blank content", alice, url.Values{"content": {""}}, http.StatusBadRequest, ""},
+		{"lifetime too long", alice, url.Values{"expires": {"custom"}, "expires_custom": {"400d"}}, http.StatusBadRequest, ""},
+		{"unknown visibility", alice, url.Values{"visibility": {"everyone"}}, http.StatusBadRequest, ""},
+		{"anonymous", ts.newBrowser(t), nil, http.StatusSeeOther, "/users/login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, _ := tt.browser.postForm(t, "/snippets/create", snippetForm(tt.changes))
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if location := header.Get("Location"); !strings.HasPrefix(location, tt.wantLocation) {
+				t.Errorf("redirected to %q; want %q", location, tt.wantLocation)
+			}
+		})
+	}
+
+	code, header, _ := alice.postForm(t, "/snippets/create", snippetForm(url.Values{"title": {"Created"}}))
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d; want %d", code, http.StatusSeeOther)
+	}
+	code, _, body := alice.get(t, header.Get("Location"))
+	if code != http.StatusOK || !strings.Contains(body, "Created") {
+		t.Errorf("viewing the new snippet: got status %d and body\n%s", code, body)
+	}
+}
+
+func TestSnippetView(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Bob", "bob@example.com")
+
+	alice := ts.newBrowser(t)
+	alice.login(t, "alice@example.com")
+	bob := ts.newBrowser(t)
+	bob.login(t, "bob@example.com")
+	anonymous := ts.newBrowser(t)
+
+	public := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Public", Content: "public content"})
+	unlisted := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Unlisted", Content: "unlisted content", Visibility: models.VisibilityUnlisted})
+	private := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Private", Content: "private content", Visibility: models.VisibilityPrivate})
+
+	tests := []struct {
+		name     string
+		browser  *browser
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"public to anyone", anonymous, "/s/" + public, http.StatusOK, "public content"},
+		{"unlisted to anyone with the link", anonymous, "/s/" + unlisted, http.StatusOK, "unlisted content"},
+		{"private to its owner", alice, "/s/" + private, http.StatusOK, "private content"},
+		{"private to another user", bob, "/s/" + private, http.StatusNotFound, ""},
+		{"private to anyone", anonymous, "/s/" + private, http.StatusNotFound, ""},
+		{"private raw to another user", bob, "/s/" + private + "/raw", http.StatusNotFound, ""},
+		{"private through the API to its owner", alice, "/api/v1/snippets/" + private, http.StatusOK, "private content"},
+		{"private through the API to another user", bob, "/api/v1/snippets/" + private, http.StatusNotFound, ""},
+		{"private through the API to anyone", anonymous, "/api/v1/snippets/" + private, http.StatusNotFound, ""},
+		{"missing", anonymous, "/s/doesnotexist", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := tt.browser.get(t, tt.path)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body doesn't contain %q:\n%s", tt.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestSnippetRawAndDownload(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	plain := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "nginx.conf", Content: "listen 80;\n"})
+	notes := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Deploy <notes>", Content: "<script>alert(1)</script>"})
+	ciphertext := "v1.tRO1pCTmsZFGLRSn.EFBtvcHuwbHsi16hyx54ghlv"
+	encrypted := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Secret", Content: ciphertext, Encrypted: true})
+
+	const text, binary = "text/plain; charset=utf-8", "application/octet-stream"
+
+	tests := []struct {
+		name            string
+		path            string
+		wantType        string
+		wantDisposition string
+		wantBody        string
+	}{
+		{"raw", "/s/" + plain + "/raw", text, "", "listen 80;\n"},
+		{"raw HTML", "/s/" + notes + "/raw", text, "", "<script>alert(1)</script>"},
+		{"raw encrypted", "/s/" + encrypted + "/raw", binary, "", ciphertext},
+		{"download", "/s/" + plain + "/download", text, "attachment; filename=nginx.conf", "listen 80;\n"},
+		{"download without extension", "/s/" + notes + "/download", text, "attachment; filename=Deploy-notes.txt", "<script>alert(1)</script>"},
+		{"download encrypted", "/s/" + encrypted + "/download", binary, "attachment; filename=Secret.txt", ciphertext},
+		{"API raw", "/api/v1/snippets/" + plain + "/raw", text, "", "listen 80;\n"},
+		{"API raw encrypted", "/api/v1/snippets/" + encrypted + "/raw", binary, "", ciphertext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.newBrowser(t).get(t, tt.path)
+			if code != http.StatusOK {
+				t.Fatalf("got status %d; want %d", code, http.StatusOK)
+			}
+			if got := header.Get("Content-Type"); got != tt.wantType {
+				t.Errorf("got Content-Type %q; want %q", got, tt.wantType)
+			}
+			if got := header.Get("Content-Disposition"); got != tt.wantDisposition {
+				t.Errorf("got Content-Disposition %q; want %q", got, tt.wantDisposition)
+			}
+			if got := header.Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("got X-Content-Type-Options %q; want nosniff", got)
+			}
+			if body != tt.wantBody {
+				t.Errorf("got body %q; want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestOnlyPublicSnippetsAreListed(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Bob", "bob@example.com")
+
+	bob := ts.newBrowser(t)
+	bob.login(t, "bob@example.com")
+	anonymous := ts.newBrowser(t)
+
+	insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Aardvark zebra", Content: "zebra"})
+	insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Buffalo zebra", Content: "zebra", Visibility: models.VisibilityUnlisted})
+	insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Cheetah zebra", Content: "zebra", Visibility: models.VisibilityPrivate})
+
+	for _, b := range []struct {
+		name    string
+		browser *browser
+	}{
+		{"anonymous", anonymous},
+		{"another user", bob},
+	} {
+		for _, path := range []string{"/", "/search?q=zebra", "/api/v1/snippets"} {
+			t.Run(b.name+" "+path, func(t *testing.T) {
+				code, _, body := b.browser.get(t, path)
+				if code != http.StatusOK {
+					t.Fatalf("got status %d; want %d", code, http.StatusOK)
+				}
+				if !strings.Contains(body, "Aardvark") {
+					t.Errorf("public snippet not listed:\n%s", body)
+				}
+				if strings.Contains(body, "Buffalo") {
+					t.Errorf("unlisted snippet listed:\n%s", body)
+				}
+				if strings.Contains(body, "Cheetah") {
+					t.Errorf("private snippet listed:\n%s", body)
+				}
+			})
+		}
+	}
+}
+
+func TestSnippetEdit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Bob", "bob@example.com")
+
+	alice := ts.newBrowser(t)
+	alice.login(t, "alice@example.com")
+	bob := ts.newBrowser(t)
+	bob.login(t, "bob@example.com")
+
+	slug := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Original", Content: "original content"})
+	path := "/s/" + slug + "/edit"
+
+	tests := []struct {
+		name      string
+		browser   *browser
+		method    string
+		changes   url.Values
+		wantCode  int
+		wantTitle string
+	}{
+		{"form for another user", bob, http.MethodGet, nil, http.StatusForbidden, "Original"},
+		{"form for anyone", ts.newBrowser(t), http.MethodGet, nil, http.StatusSeeOther, "Original"},
+		{"form for its owner", alice, http.MethodGet, nil, http.StatusOK, "Original"},
+		{"by another user", bob, http.MethodPost, url.Values{"title": {"Bob's"}}, http.StatusForbidden, "Original"},
+		{"invalid by its owner", alice, http.MethodPost, url.Values{"title": {""}}, http.StatusBadRequest, "Original"},
+		{"by its owner", alice, http.MethodPost, url.Values{"title": {"Edited"}}, http.StatusSeeOther, "Edited"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			if tt.method == http.MethodGet {
+				code, _, _ = tt.browser.get(t, path)
+			} else {
+				code, _, _ = tt.browser.postForm(t, path, snippetForm(tt.changes))
+			}
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			snippet, err := app.snippets.GetBySlug(slug)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snippet.Title != tt.wantTitle {
+				t.Errorf("got title %q; want %q", snippet.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestSnippetExtend(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	bobID := insertUser(t, app, "Bob", "bob@example.com")
+
+	alice := ts.newBrowser(t)
+	alice.login(t, "alice@example.com")
+	bob := ts.newBrowser(t)
+	bob.login(t, "bob@example.com")
+	aliceAPI := ts.newBrowser(t)
+	aliceAPI.token = insertToken(t, app, aliceID)
+	bobAPI := ts.newBrowser(t)
+	bobAPI.token = insertToken(t, app, bobID)
+
+	slug := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Runbook", Content: "restart it"})
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		browser  *browser
+		api      bool
+		expires  string
+		wantCode int
+		// wantLifetime is how long from now the snippet should then be kept,
+		// or 0 if it should still expire when it did before
+		wantLifetime time.Duration
+	}{
+		{"past the maximum", alice, false, "366d", http.StatusBadRequest, 0},
+		{"far past the maximum", alice, false, "999999w", http.StatusBadRequest, 0},
+		{"sooner than it expires", alice, false, "1d", http.StatusBadRequest, 0},
+		{"by another user", bob, false, "30d", http.StatusForbidden, 0},
+		{"by its owner", alice, false, "30d", http.StatusSeeOther, 30 * day},
+		{"past the maximum through the API", aliceAPI, true, "366d", http.StatusUnprocessableEntity, 0},
+		{"by another user through the API", bobAPI, true, "60d", http.StatusForbidden, 0},
+		{"by its owner through the API", aliceAPI, true, "60d", http.StatusOK, 60 * day},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := app.snippets.GetBySlug(slug)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var code int
+			if tt.api {
+				code, _, _ = tt.browser.postJSON(t, "/api/v1/snippets/"+slug+"/extend", map[string]string{"expires": tt.expires})
+			} else {
+				code, _, _ = tt.browser.postForm(t, "/s/"+slug+"/extend", url.Values{"expires": {"custom"}, "expires_custom": {tt.expires}})
+			}
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			after, err := app.snippets.GetBySlug(slug)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantLifetime == 0 {
+				if !after.Expires.Equal(before.Expires) {
+					t.Errorf("expiry changed from %v to %v", before.Expires, after.Expires)
+				}
+				return
+			}
+			if lifetime := time.Until(after.Expires); lifetime < tt.wantLifetime-time.Minute || lifetime > tt.wantLifetime {
+				t.Errorf("kept for %v more; want %v", lifetime, tt.wantLifetime)
+			}
+		})
+	}
+}
+
+func TestSnippetDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Bob", "bob@example.com")
+
+	alice := ts.newBrowser(t)
+	alice.login(t, "alice@example.com")
+	bob := ts.newBrowser(t)
+	bob.login(t, "bob@example.com")
+
+	slug := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "Doomed", Content: "soon gone"})
+	path := "/s/" + slug + "/delete"
+
+	tests := []struct {
+		name        string
+		browser     *browser
+		wantCode    int
+		wantDeleted bool
+	}{
+		{"by anyone", ts.newBrowser(t), http.StatusSeeOther, false},
+		{"by another user", bob, http.StatusForbidden, false},
+		{"by its owner", alice, http.StatusSeeOther, true},
+		{"again", alice, http.StatusNotFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := tt.browser.postForm(t, path, url.Values{})
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			_, err := app.snippets.GetBySlug(slug)
+			if deleted := errors.Is(err, models.ErrNoRecord); deleted != tt.wantDeleted {
+				t.Errorf("got deleted %t (%v); want %t", deleted, err, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestBurnAfterReadingIsBurnedOnceOnView(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	insertUser(t, app, "Bob", "bob@example.com")
+
+	alice := ts.newBrowser(t)
+	alice.login(t, "alice@example.com")
+	bob := ts.newBrowser(t)
+	bob.login(t, "bob@example.com")
+	anonymous := ts.newBrowser(t)
+
+	slug := insertSnippet(t, app, &models.Snippet{
+		UserID:           aliceID,
+		Title:            "Burn me",
+		Content:          "only once",
+		BurnAfterReading: true,
+	})
+
+	steps := []struct {
+		name     string
+		browser  *browser
+		path     string
+		wantCode int
+	}{
+		{"non-owner edit", bob, "/s/" + slug + "/edit", http.StatusForbidden},
+		{"non-owner extend", bob, "/s/" + slug + "/extend", http.StatusForbidden},
+		{"non-owner delete", bob, "/s/" + slug + "/delete", http.StatusForbidden},
+		{"non-owner history", bob, "/s/" + slug + "/history", http.StatusForbidden},
+		{"non-owner diff", bob, "/s/" + slug + "/diff", http.StatusForbidden},
+		{"owner view", alice, "/s/" + slug, http.StatusOK},
+		{"owner history", alice, "/s/" + slug + "/history", http.StatusOK},
+		{"first view", anonymous, "/s/" + slug, http.StatusOK},
+		{"second view", bob, "/s/" + slug, http.StatusGone},
+		{"raw after burning", anonymous, "/s/" + slug + "/raw", http.StatusGone},
+	}
+
+	for _, step := range steps {
+		code, _, body := step.browser.get(t, step.path)
+		if code != step.wantCode {
+			t.Fatalf("%s: got status %d; want %d", step.name, code, step.wantCode)
+		}
+		if step.name == "first view" && !strings.Contains(body, "only once") {
+			t.Errorf("%s: snippet content not found in body", step.name)
+		}
+	}
+}
+
+func TestBurnAfterReadingThroughTheAPI(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	bobID := insertUser(t, app, "Bob", "bob@example.com")
+
+	token, err := app.tokens.Insert(bobID, "test", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := ts.newBrowser(t)
+	bob.token = token
+
+	slug := insertSnippet(t, app, &models.Snippet{
+		UserID:           aliceID,
+		Title:            "Burn me",
+		Content:          "only once",
+		BurnAfterReading: true,
+	})
+
+	code, _, _ := bob.do(t, http.MethodPost, "/api/v1/snippets/"+slug+"/extend", strings.NewReader(`{"expires": "30d"}`))
+	if code != http.StatusForbidden {
+		t.Fatalf("non-owner extend: got status %d; want %d", code, http.StatusForbidden)
+	}
+	code, _, _ = bob.do(t, http.MethodDelete, "/api/v1/snippets/"+slug, nil)
+	if code != http.StatusForbidden {
+		t.Fatalf("non-owner delete: got status %d; want %d", code, http.StatusForbidden)
+	}
+
+	code, _, body := bob.get(t, "/api/v1/snippets/"+slug)
+	if code != http.StatusOK || !strings.Contains(body, "only once") {
+		t.Fatalf("first view: got status %d and body %s", code, body)
+	}
+	code, _, _ = bob.get(t, "/api/v1/snippets/"+slug)
+	if code != http.StatusGone {
+		t.Fatalf("second view: got status %d; want %d", code, http.StatusGone)
+	}
+}
+
+func TestParallelWrongPassphrasesAreLimited(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+
+	snippet := &models.Snippet{UserID: aliceID, Title: "Locked", Content: "secret"}
+	if err := snippet.SetPassphrase("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	slug := insertSnippet(t, app, snippet)
+
+	const guesses = 20
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/snippets/"+slug, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Set("Snippet-Passphrase", "wrong")
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rs.Body.Close()
+			codes <- rs.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	// only the guesses that were let through are checked and answered with
+	// 403, the rest are refused up front
+	checked := 0
+	for code := range codes {
+		switch code {
+		case http.StatusForbidden:
+			checked++
+		case http.StatusTooManyRequests:
+		default:
+			t.Errorf("got unexpected status %d", code)
+		}
+	}
+	if checked != 5 {
+		t.Errorf("%d guesses reached the passphrase check; want 5", checked)
+	}
+}
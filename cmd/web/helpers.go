@@ -5,7 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"runtime/debug"
+	"strings"
+	"unicode"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
 )
 
 // serverError helper writes an error messaeg and stack trace to the errorLog,
@@ -51,6 +56,49 @@ func (app *application) render(w http.ResponseWriter, status int, page string, d
 	buf.WriteTo(w)
 }
 
+// authenticatedUserID returns the id of the user making the request, either
+// from a bearer token checked by authenticateToken or from their session. It
+// returns 0 if nobody is logged in.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if id, ok := r.Context().Value(tokenUserIDContextKey).(int); ok {
+		return id
+	}
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
 func (app *application) isAuthenticated(r *http.Request) bool {
-	return app.sessionManager.Exists(r.Context(), "authenticatedUserID")
+	return app.authenticatedUserID(r) != 0
+}
+
+// snippetFilename turns a snippet's title into a safe file name, keeping any
+// extension the title already has and adding .txt otherwise.
+func snippetFilename(snippet *models.Snippet) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '.', r == '-', r == '_':
+			return r
+		case unicode.IsSpace(r):
+			return '-'
+		default:
+			return -1
+		}
+	}, snippet.Title)
+	name = strings.Trim(name, ".-")
+
+	if name == "" {
+		name = "snippet-" + snippet.Slug
+	}
+	if filepath.Ext(name) == "" {
+		name += ".txt"
+	}
+	return name
+}
+
+// snippetContentType is the Content-Type a snippet's content is served as on
+// its own. Content encrypted in the browser is ciphertext, not text to read.
+func snippetContentType(snippet *models.Snippet) string {
+	if snippet.Encrypted {
+		return "application/octet-stream"
+	}
+	return "text/plain; charset=utf-8"
 }
@@ -1,31 +1,46 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
-	"database/sql"
+	"errors"
 	"flag"
+	"html/template"
 	"log"
 	"net/http"
 	"os"
-	"text/template"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/Yusufdot101/snippetbox/internal/expiry"
+	"github.com/Yusufdot101/snippetbox/internal/mailer"
+	"github.com/Yusufdot101/snippetbox/internal/migrations"
 	"github.com/Yusufdot101/snippetbox/internal/models"
-	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/Yusufdot101/snippetbox/internal/search"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 )
 
 type application struct {
 	errorLog       *log.Logger
 	infoLog        *log.Logger
-	snippets       *models.SnippetModel
-	users          *models.UserModel
+	snippets       models.SnippetStore
+	searchIndex    search.Index
+	users          models.UserStore
+	tokens         models.TokenStore
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
+	unlockLimiter  *failureLimiter
+	expiryPolicy   expiry.Policy
+	notifications  models.NotificationStore
+	mailer         mailer.Mailer
+	baseURL        string
+	linkKey        []byte
 }
 
 func main() {
@@ -45,15 +60,77 @@ func main() {
 	// short text explaining what the flag controls
 	// store in appropriate variable
 	addr := flag.String("addr", defaultPort, "HTTP newtwork address")
-	dsn := flag.String("dsn", defaultDSN, "MySQL data source name")
+	dsn := flag.String("dsn", defaultDSN, `data source name: "mysql://...", "postgres://...", "sqlite:///path/to/snippetbox.db", or "memory" to keep everything in memory`)
+	autoMigrate := flag.Bool("auto-migrate", false, "Apply pending database migrations before starting")
+	keyFile := flag.String("key-file", "", "File with the keys snippets are encrypted with in the database, instead of $"+keysEnv)
+	encryptTitles := flag.Bool("encrypt-titles", false, "Encrypt snippet titles as well as their content")
+	cleanupInterval := flag.Duration("cleanup-interval", time.Hour, "How often to remove expired snippets, or 0 to never remove them")
+	cleanupBatch := flag.Int("cleanup-batch", 500, "Number of expired snippets to remove per transaction")
+	archiveExpired := flag.Bool("archive-expired", false, "Move expired snippets to the archive instead of deleting them")
+	maxExpiry := flag.String("max-expiry", "365d", "Longest lifetime a snippet can be given, such as 72h or 30d")
+	allowNever := flag.Bool("allow-never", true, "Allow snippets that never expire")
+	reminderLead := flag.Duration("reminder-lead", 72*time.Hour, "How long before a snippet expires to remind its owner, or 0 to never remind them")
+	reminderInterval := flag.Duration("reminder-interval", 15*time.Minute, "How often to look for snippets to send reminders about")
+	mailerKind := flag.String("mailer", "", `How to email reminders: "smtp", "log" to only log them, or "" to not email them`)
+	smtpAddr := flag.String("smtp-addr", "localhost:25", "SMTP server address, with $SMTP_PASSWORD as the password if -smtp-username is set")
+	smtpFrom := flag.String("smtp-from", "Snippetbox <no-reply@localhost>", "Address reminders are sent from")
+	smtpUsername := flag.String("smtp-username", "", "SMTP username")
+	baseURL := flag.String("base-url", "", "Public URL of the site, used for links in emails (defaults to https://localhost plus -addr)")
 	flag.Parse()
 
-	db, err := openDB(*dsn)
+	if *cleanupBatch < 1 {
+		errorLog.Fatal("-cleanup-batch must be at least 1")
+	}
+	maxLifetime, never, err := expiry.Parse(*maxExpiry)
+	if err != nil || never || maxLifetime < expiry.Min {
+		errorLog.Fatalf("-max-expiry must be a lifetime of at least %s", expiry.Format(expiry.Min))
+	}
+
+	// without keys, snippets are stored in plaintext
+	keys, err := loadKeys(*keyFile)
 	if err != nil {
 		errorLog.Fatal(err)
 	}
 
-	defer db.Close()
+	// "migrate up", "migrate down N" and "migrate status" manage the database
+	// schema and exit instead of starting the server
+	if flag.Arg(0) == "migrate" {
+		if *dsn == "memory" {
+			errorLog.Fatal("the in-memory store has no schema to migrate")
+		}
+
+		db, dialect, err := openDB(*dsn)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+
+		err = runMigrate(&migrations.Migrator{DB: db, Dialect: dialect}, flag.Args()[1:], infoLog)
+		db.Close()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+
+	// "rekey" re-encrypts the stored snippets under the current key and exits
+	if flag.Arg(0) == "rekey" {
+		if *dsn == "memory" {
+			errorLog.Fatal("the in-memory store keeps nothing to re-encrypt")
+		}
+
+		db, dialect, err := openDB(*dsn)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+
+		snippets := &models.SnippetModel{DB: db, Dialect: dialect, Keys: keys, EncryptTitles: *encryptTitles}
+		err = runRekey(snippets, flag.Args()[1:], infoLog)
+		db.Close()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
 
 	templateCache, err := newTemplateCache()
 	if err != nil {
@@ -63,18 +140,81 @@ func main() {
 	formDecoder := form.NewDecoder()
 
 	sessionManager := scs.New()
-	sessionManager.Store = mysqlstore.New(db)
 	sessionManager.Lifetime = 12 * time.Hour
 
 	app := &application{
 		errorLog:       errorLog,
 		infoLog:        infoLog,
-		snippets:       &models.SnippetModel{DB: db},
-		users:          &models.UserModel{DB: db},
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+		// five wrong passphrases lock a snippet for up to a quarter of an hour
+		unlockLimiter: newFailureLimiter(5, 15*time.Minute),
+		expiryPolicy:  expiry.Policy{Max: maxLifetime, AllowNever: *allowNever},
+		baseURL:       strings.TrimSuffix(*baseURL, "/"),
 	}
+	if app.baseURL == "" {
+		app.baseURL = "https://localhost" + *addr
+	}
+
+	linkKey, configured, err := loadLinkKey()
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+	if !configured && *reminderLead > 0 {
+		infoLog.Printf("$%s isn't set, so the extend links in reminders stop working when the server restarts", linkSecretEnv)
+	}
+	app.linkKey = linkKey
+
+	switch *mailerKind {
+	case "":
+		// reminders are only shown in the app
+	case "smtp":
+		app.mailer = &mailer.SMTP{Addr: *smtpAddr, From: *smtpFrom, Username: *smtpUsername, Password: os.Getenv("SMTP_PASSWORD")}
+	case "log":
+		app.mailer = &mailer.Log{Logger: infoLog}
+	default:
+		errorLog.Fatalf("unknown mailer %q", *mailerKind)
+	}
+
+	// the in-memory stores need no database server, which makes them handy
+	// for development; sessions then use scs's default in-memory store too
+	if *dsn == "memory" {
+		memory := models.NewMemoryModels()
+		app.snippets, app.users, app.tokens = memory.Snippets, memory.Users, memory.Tokens
+		app.notifications = memory.Notifications
+	} else {
+		db, dialect, err := openDB(*dsn)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+
+		defer db.Close()
+
+		if *autoMigrate {
+			applied, err := (&migrations.Migrator{DB: db, Dialect: dialect}).Up()
+			if err != nil {
+				errorLog.Fatal(err)
+			}
+			infoLog.Printf("Applied %d migration(s)", len(applied))
+		}
+
+		sessionManager.Store = newSessionStore(db, dialect)
+		app.snippets = &models.SnippetModel{DB: db, Dialect: dialect, Keys: keys, EncryptTitles: *encryptTitles}
+		app.users = &models.UserModel{DB: db, Dialect: dialect}
+		app.tokens = &models.TokenModel{DB: db, Dialect: dialect}
+		app.notifications = &models.NotificationModel{DB: db, Dialect: dialect}
+	}
+
+	// the search index is kept in memory, so it is rebuilt from the stored
+	// snippets on every start and then kept up to date as they change
+	app.searchIndex = search.NewMemory()
+	indexed, err := buildSearchIndex(app.snippets, app.searchIndex)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+	infoLog.Printf("Indexed %d snippet(s) for search", indexed)
+	app.snippets = &indexedSnippets{SnippetStore: app.snippets, index: app.searchIndex, errorLog: errorLog}
 
 	// initialize a new http.Server struct. we set the Addr and Handler fields so
 	// that the server uses the same network address and routes as before, and set
@@ -99,21 +239,46 @@ func main() {
 	// write the messages using custom loggers
 	infoLog.Printf("Server listening on port: %s", *addr)
 
-	// Call the ListenAndServe() method on our new http.Server struct.
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
+	// an interrupt or SIGTERM stops the server and the cleanup worker,
+	// letting requests and the batch in progress finish first
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	errorLog.Fatal(err)
-}
+	var workers sync.WaitGroup
+	if *cleanupInterval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			app.cleanupExpired(ctx, *cleanupInterval, *cleanupBatch, *archiveExpired)
+		}()
+	}
+	if *reminderLead > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			app.remindExpiring(ctx, *reminderInterval, *reminderLead)
+		}()
+	}
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		infoLog.Print("Shutting down")
 
-	if err != nil {
-		return nil, err
-	}
-	if err = db.Ping(); err != nil {
-		return nil, err
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	}()
+
+	// Call the ListenAndServe() method on our new http.Server struct.
+	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
+	if !errors.Is(err, http.ErrServerClosed) {
+		errorLog.Fatal(err)
 	}
 
-	return db, nil
+	if err = <-shutdownErr; err != nil {
+		errorLog.Print(err)
+	}
+	workers.Wait()
+	infoLog.Print("Server stopped")
 }
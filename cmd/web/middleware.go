@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
 
 	"github.com/justinas/nosurf"
 )
@@ -57,9 +62,74 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// requireAPIAuthentication is the API counterpart of requireAuthentication.
+// Rather than redirecting to the login page it responds with a JSON 401.
+func (app *application) requireAPIAuthentication(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiClientError(w, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Add("Cache-Control", "no-store")
+
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+type contextKey string
+
+// tokenUserIDContextKey holds the id of the user whose bearer token
+// authenticated the request.
+const tokenUserIDContextKey = contextKey("tokenUserID")
+
+// authenticateToken checks the personal access token in an
+// "Authorization: Bearer" header and, if it is valid, marks the request as
+// made by the token's owner. Requests without the header are left to the
+// session. A bad token is rejected outright rather than falling back to the
+// session cookie.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Authorization")
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			app.apiClientError(w, http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := app.tokens.Authenticate(token)
+		if err != nil {
+			if errors.Is(err, models.ErrInvaildCredentials) {
+				app.apiClientError(w, http.StatusUnauthorized)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tokenUserIDContextKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
+
 func noSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 
+	// a bearer token can't be attached to a cross-site request by the
+	// browser, so requests it has authenticated don't need a CSRF token
+	csrfHandler.ExemptFunc(func(r *http.Request) bool {
+		_, ok := r.Context().Value(tokenUserIDContextKey).(int)
+		return ok
+	})
+
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
 		Path:     "/",
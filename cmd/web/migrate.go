@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/Yusufdot101/snippetbox/internal/migrations"
+)
+
+const migrateUsage = "usage: migrate up | migrate down N | migrate status"
+
+// runMigrate carries out the "migrate" subcommand named by args.
+func runMigrate(migrator *migrations.Migrator, args []string, infoLog *log.Logger) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			infoLog.Printf("Applied %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			infoLog.Print("Database is already up to date")
+		}
+
+	case "down":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("migrate down: %q is not a positive number", args[1])
+		}
+
+		rolledBack, err := migrator.Down(n)
+		for _, migration := range rolledBack {
+			infoLog.Printf("Rolled back %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-32s %s\n", status.Version, status.Name, state)
+		}
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// failureLimiter counts failed attempts per key, such as wrong passphrases for
+// a snippet, and refuses further attempts once max of them have failed within
+// window. It is safe for concurrent use.
+//
+// Attempts are counted as failures from the moment they are reserved, before
+// they are checked, so that however many are made at once no more than max
+// get to be checked. Those that turn out to succeed are refunded.
+type failureLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	failures map[string][]time.Time
+}
+
+func newFailureLimiter(max int, window time.Duration) *failureLimiter {
+	return &failureLimiter{
+		max:      max,
+		window:   window,
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// Reserve reports whether another attempt may be made for key and, if so,
+// counts it as failed until it is refunded. The returned time identifies the
+// attempt to Refund.
+func (l *failureLimiter) Reserve(key string) (at time.Time, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failures := l.recent(key)
+	if len(failures) >= l.max {
+		return time.Time{}, false
+	}
+
+	at = time.Now()
+	l.failures[key] = append(failures, at)
+	return at, true
+}
+
+// Refund takes back the attempt for key reserved at at, once it has
+// succeeded.
+func (l *failureLimiter) Refund(key string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failures := l.recent(key)
+	for i, t := range failures {
+		if t.Equal(at) {
+			failures = append(failures[:i:i], failures[i+1:]...)
+			break
+		}
+	}
+
+	if len(failures) == 0 {
+		delete(l.failures, key)
+	} else {
+		l.failures[key] = failures
+	}
+}
+
+// recent drops the failures for key that are older than the window and
+// returns the rest. The caller must hold l.mu.
+func (l *failureLimiter) recent(key string) []time.Time {
+	failures := l.failures[key]
+
+	cutoff := time.Now().Add(-l.window)
+	i := 0
+	for i < len(failures) && failures[i].Before(cutoff) {
+		i++
+	}
+	failures = failures[i:]
+
+	if len(failures) == 0 {
+		delete(l.failures, key)
+	} else {
+		l.failures[key] = failures
+	}
+	return failures
+}
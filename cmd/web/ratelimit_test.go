@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailureLimiterLocksOutAfterMax(t *testing.T) {
+	l := newFailureLimiter(3, time.Hour)
+
+	for i := range 3 {
+		if _, ok := l.Reserve("a"); !ok {
+			t.Fatalf("attempt %d was refused", i+1)
+		}
+	}
+	if _, ok := l.Reserve("a"); ok {
+		t.Error("attempt 4 was allowed after 3 failures")
+	}
+	if _, ok := l.Reserve("b"); !ok {
+		t.Error("another key was locked out too")
+	}
+}
+
+func TestFailureLimiterRefundsSuccesses(t *testing.T) {
+	l := newFailureLimiter(2, time.Hour)
+
+	for i := range 5 {
+		at, ok := l.Reserve("a")
+		if !ok {
+			t.Fatalf("attempt %d was refused although the others were refunded", i+1)
+		}
+		l.Refund("a", at)
+	}
+}
+
+func TestFailureLimiterForgetsOldFailures(t *testing.T) {
+	l := newFailureLimiter(1, 50*time.Millisecond)
+
+	l.Reserve("a")
+	if _, ok := l.Reserve("a"); ok {
+		t.Fatal("second attempt was allowed within the window")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := l.Reserve("a"); !ok {
+		t.Error("attempt was refused after the window had passed")
+	}
+}
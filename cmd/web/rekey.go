@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/Yusufdot101/snippetbox/internal/keyring"
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+// keysEnv names the environment variable keys can be given in instead of a
+// key file, in the format the keyring package documents.
+const keysEnv = "SNIPPETBOX_KEYS"
+
+// loadKeys reads the keys snippets are encrypted with from the file at path,
+// or from the environment if path is empty. It returns a nil keyring, which
+// stores snippets in plaintext, if neither is set.
+func loadKeys(path string) (*keyring.Keyring, error) {
+	if path != "" {
+		return keyring.Load(path)
+	}
+	if spec := os.Getenv(keysEnv); spec != "" {
+		return keyring.Parse(spec)
+	}
+	return nil, nil
+}
+
+// runRekey carries out the "rekey" subcommand: it re-encrypts every snippet
+// and revision that isn't stored under the current key, batch by batch. To
+// rotate keys, put a new key first in the key file, keep the old ones after
+// it, run rekey and then remove the old keys.
+func runRekey(snippets *models.SnippetModel, args []string, infoLog *log.Logger) error {
+	flags := flag.NewFlagSet("rekey", flag.ContinueOnError)
+	batch := flags.Int("batch", 500, "Number of rows to re-encrypt per transaction")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	total := 0
+	for {
+		n, err := snippets.Rekey(*batch)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		total += n
+		infoLog.Printf("Re-encrypted %d row(s)", total)
+	}
+
+	if current := snippets.Keys.Current(); current != "" {
+		infoLog.Printf("Every snippet is encrypted with key %q", current)
+	} else {
+		infoLog.Print("No keys are configured, every snippet is stored in plaintext")
+	}
+	return nil
+}
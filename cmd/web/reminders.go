@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/mailer"
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+// reminderBatch is how many reminders emailReminders fetches at a time.
+const reminderBatch = 100
+
+// linkSecretEnv names the environment variable holding the secret the extend
+// links in reminders are signed with.
+const linkSecretEnv = "SNIPPETBOX_LINK_SECRET"
+
+// loadLinkKey returns the key extend links are signed with, and whether it
+// came from the environment. Without one there, a random key is made, and the
+// links in reminders emailed before a restart stop working.
+func loadLinkKey() (key []byte, configured bool, err error) {
+	if secret := os.Getenv(linkSecretEnv); secret != "" {
+		return []byte(secret), true, nil
+	}
+	key = make([]byte, 32)
+	_, err = rand.Read(key)
+	return key, false, err
+}
+
+// remindExpiring notifies owners of the snippets that expire within lead
+// every interval until ctx is done. Notifications are shown in the app and,
+// if there is a mailer, emailed as well.
+func (app *application) remindExpiring(ctx context.Context, interval, lead time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.queueReminders(lead)
+		if app.mailer != nil {
+			app.emailReminders(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) queueReminders(lead time.Duration) {
+	n, err := app.notifications.QueueExpiryReminders(lead)
+	if err != nil {
+		app.errorLog.Printf("queueing expiry reminders: %v", err)
+		return
+	}
+	if n > 0 {
+		app.infoLog.Printf("Queued %d expiry reminder(s)", n)
+	}
+}
+
+// emailReminders emails the reminders that haven't been yet. It gives up at
+// the first failure, as the mail server is likely down, and the rest are
+// tried again next time.
+func (app *application) emailReminders(ctx context.Context) {
+	sent := 0
+	defer func() {
+		if sent > 0 {
+			app.infoLog.Printf("Emailed %d expiry reminder(s)", sent)
+		}
+	}()
+
+	for ctx.Err() == nil {
+		notifications, err := app.notifications.Unemailed(reminderBatch)
+		if err != nil {
+			app.errorLog.Printf("emailing expiry reminders: %v", err)
+			return
+		}
+
+		for _, notification := range notifications {
+			if err := app.emailReminder(notification); err != nil {
+				app.errorLog.Printf("emailing expiry reminder %d: %v", notification.ID, err)
+				return
+			}
+			sent++
+		}
+
+		if len(notifications) < reminderBatch {
+			return
+		}
+	}
+}
+
+func (app *application) emailReminder(notification *models.Notification) error {
+	user, err := app.users.Get(notification.UserID)
+	if err != nil {
+		return err
+	}
+	snippet, err := app.snippets.Get(notification.SnippetID)
+	if err != nil {
+		// it expired or was deleted since, so there's nothing left to say
+		if errors.Is(err, models.ErrNoRecord) {
+			return app.notifications.MarkEmailed(notification.ID)
+		}
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your snippet is about to expire",
+		Body: fmt.Sprintf(`Hi %s,
+
+Your snippet %q expires on %s. If you still need it, this link keeps it
+for another %s:
+
+%s
+
+Your other snippets that are about to expire are listed at
+%s/notifications
+`, user.Name, snippet.Title, humanDate(snippet.Expires), app.expiryPolicy.Default(), app.extendURL(snippet), app.baseURL),
+	}
+	if err := app.mailer.Send(msg); err != nil {
+		return err
+	}
+
+	return app.notifications.MarkEmailed(notification.ID)
+}
+
+// extendURL returns the link that extends snippet by the default lifetime in
+// one click, without logging in first. See snippetExtendLink.
+func (app *application) extendURL(snippet *models.Snippet) string {
+	value := app.expiryPolicy.Default()
+	return app.baseURL + "/s/" + snippet.Slug + "/extend/" + app.extendSignature(snippet, value) + "?expires=" + url.QueryEscape(value)
+}
+
+// extendSignature signs the request to extend snippet by the lifetime value.
+// The signature covers the snippet's current expiry, so it stops matching
+// once the snippet has been extended.
+func (app *application) extendSignature(snippet *models.Snippet, value string) string {
+	mac := hmac.New(sha256.New, app.linkKey)
+	fmt.Fprintf(mac, "extend\x00%s\x00%s\x00%d", snippet.Slug, value, snippet.Expires.Unix())
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
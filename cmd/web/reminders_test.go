@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/mailer"
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+// recordingMailer keeps the messages it is asked to send.
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var extendLinkRX = regexp.MustCompile(`https://\S+/extend/\S+`)
+
+func TestReminderExtendLink(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	mail := &recordingMailer{}
+	app.mailer, app.baseURL = mail, ts.URL
+
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+	slug, err := app.snippets.Insert(&models.Snippet{
+		UserID:     aliceID,
+		Title:      "Runbook",
+		Content:    "restart it",
+		Visibility: models.VisibilityPrivate,
+	}, time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := func() time.Time {
+		t.Helper()
+		snippet, err := app.snippets.GetBySlug(slug)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return snippet.Expires
+	}
+
+	// however often reminders are looked for, one is sent per expiry
+	for range 3 {
+		app.queueReminders(72 * time.Hour)
+		app.emailReminders(context.Background())
+	}
+	if len(mail.sent) != 1 {
+		t.Fatalf("sent %d reminders; want 1", len(mail.sent))
+	}
+	link := extendLinkRX.FindString(mail.sent[0].Body)
+	if link == "" {
+		t.Fatalf("no extend link in the reminder:\n%s", mail.sent[0].Body)
+	}
+	path := strings.TrimPrefix(link, ts.URL)
+
+	tampered, err := url.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered.RawQuery = url.Values{"expires": {"366d"}}.Encode()
+
+	anonymous := ts.newBrowser(t)
+	before := expires()
+
+	code, header, _ := anonymous.get(t, tampered.String())
+	if code != http.StatusSeeOther || header.Get("Location") != "/s/"+slug+"/extend" {
+		t.Errorf("tampered link: got status %d to %q; want %d to the extend form", code, header.Get("Location"), http.StatusSeeOther)
+	}
+	if !expires().Equal(before) {
+		t.Fatal("tampered link extended the snippet")
+	}
+
+	code, header, _ = anonymous.get(t, path)
+	if code != http.StatusSeeOther || header.Get("Location") != "/" {
+		t.Errorf("link: got status %d to %q; want %d to /", code, header.Get("Location"), http.StatusSeeOther)
+	}
+	after := expires()
+	if lifetime := time.Until(after); lifetime < 364*24*time.Hour {
+		t.Errorf("link kept the snippet for %v more; want the default of 365 days", lifetime)
+	}
+
+	// the link was for the old expiry, so it can't be used again
+	code, header, _ = anonymous.get(t, path)
+	if code != http.StatusSeeOther || header.Get("Location") != "/s/"+slug+"/extend" {
+		t.Errorf("used link: got status %d to %q; want %d to the extend form", code, header.Get("Location"), http.StatusSeeOther)
+	}
+	if !expires().Equal(after) {
+		t.Error("used link extended the snippet again")
+	}
+}
@@ -18,7 +18,20 @@ func (app *application) routes() http.Handler {
 	dynamic := alice.New(app.sessionManager.LoadAndSave, noSurf)
 
 	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
-	router.Handler(http.MethodGet, "/snippets/view/:id", dynamic.ThenFunc(app.snippetView))
+	router.Handler(http.MethodGet, "/search", dynamic.ThenFunc(app.snippetSearch))
+	router.Handler(http.MethodGet, "/s/:slug", dynamic.ThenFunc(app.snippetView))
+	router.Handler(http.MethodGet, "/s/:slug/history", dynamic.ThenFunc(app.snippetHistory))
+	router.Handler(http.MethodGet, "/s/:slug/diff", dynamic.ThenFunc(app.snippetDiff))
+	router.Handler(http.MethodGet, "/s/:slug/raw", dynamic.ThenFunc(app.snippetRaw))
+	router.Handler(http.MethodGet, "/s/:slug/download", dynamic.ThenFunc(app.snippetDownload))
+	router.Handler(http.MethodPost, "/s/:slug/unlock", dynamic.ThenFunc(app.snippetUnlockPost))
+
+	// Links from before snippets had slugs
+	router.Handler(http.MethodGet, "/snippets/view/:id", dynamic.ThenFunc(app.snippetRedirect("")))
+	router.Handler(http.MethodGet, "/snippets/view/:id/history", dynamic.ThenFunc(app.snippetRedirect("/history")))
+	router.Handler(http.MethodGet, "/snippets/view/:id/diff", dynamic.ThenFunc(app.snippetRedirect("/diff")))
+	router.Handler(http.MethodGet, "/snippets/raw/:id", dynamic.ThenFunc(app.snippetRedirect("/raw")))
+	router.Handler(http.MethodGet, "/snippets/download/:id", dynamic.ThenFunc(app.snippetRedirect("/download")))
 
 	router.Handler(http.MethodGet, "/users/signup", dynamic.ThenFunc(app.userSignup))
 	router.Handler(http.MethodPost, "/users/signup", dynamic.ThenFunc(app.userSignupPost))
@@ -29,7 +42,33 @@ func (app *application) routes() http.Handler {
 
 	router.Handler(http.MethodGet, "/snippets/create", protected.ThenFunc(app.snippetCreate))
 	router.Handler(http.MethodPost, "/snippets/create", protected.ThenFunc(app.snippetCreatePost))
+	router.Handler(http.MethodGet, "/snippets/mine", protected.ThenFunc(app.snippetMine))
+	router.Handler(http.MethodGet, "/notifications", protected.ThenFunc(app.notificationList))
+	router.Handler(http.MethodGet, "/s/:slug/edit", protected.ThenFunc(app.snippetEdit))
+	router.Handler(http.MethodPost, "/s/:slug/edit", protected.ThenFunc(app.snippetEditPost))
+	router.Handler(http.MethodGet, "/s/:slug/extend", protected.ThenFunc(app.snippetExtend))
+	router.Handler(http.MethodPost, "/s/:slug/extend", protected.ThenFunc(app.snippetExtendPost))
+	router.Handler(http.MethodGet, "/s/:slug/extend/:signature", dynamic.ThenFunc(app.snippetExtendLink))
+	router.Handler(http.MethodGet, "/s/:slug/delete", protected.ThenFunc(app.snippetDelete))
+	router.Handler(http.MethodPost, "/s/:slug/delete", protected.ThenFunc(app.snippetDeletePost))
 	router.Handler(http.MethodPost, "/users/logout", protected.ThenFunc(app.userLogoutPost))
+	router.Handler(http.MethodGet, "/users/settings", protected.ThenFunc(app.userSettings))
+	router.Handler(http.MethodPost, "/users/settings/tokens", protected.ThenFunc(app.tokenCreatePost))
+	router.Handler(http.MethodPost, "/users/settings/tokens/:id/revoke", protected.ThenFunc(app.tokenRevokePost))
+
+	// The JSON API shares the session with the HTML pages, but also accepts
+	// personal access tokens and answers with JSON errors instead of
+	// redirecting to the login page.
+	api := alice.New(app.sessionManager.LoadAndSave, app.authenticateToken, noSurf)
+	apiProtected := api.Append(app.requireAPIAuthentication)
+
+	router.Handler(http.MethodGet, "/api/v1/snippets", api.ThenFunc(app.apiSnippetList))
+	router.Handler(http.MethodGet, "/api/v1/snippets/:id", api.ThenFunc(app.apiSnippetView))
+	router.Handler(http.MethodGet, "/api/v1/snippets/:id/raw", api.ThenFunc(app.apiSnippetRaw))
+	router.Handler(http.MethodGet, "/api/v1/me/snippets", apiProtected.ThenFunc(app.apiSnippetMine))
+	router.Handler(http.MethodPost, "/api/v1/snippets", apiProtected.ThenFunc(app.apiSnippetCreate))
+	router.Handler(http.MethodPost, "/api/v1/snippets/:id/extend", apiProtected.ThenFunc(app.apiSnippetExtend))
+	router.Handler(http.MethodDelete, "/api/v1/snippets/:id", apiProtected.ThenFunc(app.apiSnippetDelete))
 
 	// Create a middleware chain containing our 'standard' middleware
 	// which will be used for every request our application receives.
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+	"github.com/Yusufdot101/snippetbox/internal/search"
+)
+
+// indexBatch is how many snippets are read at a time while building the
+// search index.
+const indexBatch = 500
+
+// buildSearchIndex adds every snippet that hasn't expired to index and
+// returns how many it added.
+func buildSearchIndex(snippets models.SnippetStore, index search.Index) (int, error) {
+	total, afterID := 0, 0
+	for {
+		batch, err := snippets.After(afterID, indexBatch)
+		if err != nil {
+			return total, err
+		}
+		for _, snippet := range batch {
+			if err := index.Add(searchDocument(snippet)); err != nil {
+				return total, err
+			}
+			afterID = snippet.ID
+		}
+		total += len(batch)
+		if len(batch) < indexBatch {
+			return total, nil
+		}
+	}
+}
+
+// searchDocument describes snippet to the search index. Only public snippets
+// can be found by everyone, as unlisted ones are only for those who have the
+// link and burn after reading ones would be gone once read.
+func searchDocument(snippet *models.Snippet) search.Document {
+	return search.Document{
+		ID:       snippet.ID,
+		UserID:   snippet.UserID,
+		Public:   snippet.Visibility == models.VisibilityPublic && !snippet.BurnAfterReading,
+		Expires:  snippet.Expires,
+		Title:    snippet.Title,
+		Language: snippet.Language,
+		Content:  searchableContent(snippet),
+	}
+}
+
+// searchableContent returns the part of snippet's content that may be
+// searched and quoted in results: none of it when it was encrypted in the
+// browser, as it is ciphertext, or when it needs a passphrase to be read.
+func searchableContent(snippet *models.Snippet) string {
+	if snippet.Encrypted || snippet.Protected() {
+		return ""
+	}
+	return snippet.Content
+}
+
+// indexedSnippets keeps index up to date as snippets are written through it.
+// Failing to update the index doesn't fail the write, it is only logged: the
+// snippet is saved, and search results are checked against the store anyway.
+type indexedSnippets struct {
+	models.SnippetStore
+	index    search.Index
+	errorLog *log.Logger
+}
+
+func (s *indexedSnippets) Insert(snippet *models.Snippet, expires time.Time) (string, error) {
+	slug, err := s.SnippetStore.Insert(snippet, expires)
+	if err != nil {
+		return "", err
+	}
+	s.reindex(s.SnippetStore.GetBySlug(slug))
+	return slug, nil
+}
+
+func (s *indexedSnippets) Update(snippet *models.Snippet, userID int, expires time.Time) error {
+	if err := s.SnippetStore.Update(snippet, userID, expires); err != nil {
+		return err
+	}
+	s.reindex(s.SnippetStore.Get(snippet.ID))
+	return nil
+}
+
+func (s *indexedSnippets) Extend(id int, expires time.Time) error {
+	if err := s.SnippetStore.Extend(id, expires); err != nil {
+		return err
+	}
+	s.reindex(s.SnippetStore.Get(id))
+	return nil
+}
+
+func (s *indexedSnippets) Delete(id int) error {
+	if err := s.SnippetStore.Delete(id); err != nil {
+		return err
+	}
+	s.logError(s.index.Remove(id))
+	return nil
+}
+
+func (s *indexedSnippets) Burn(slug string) (*models.Snippet, error) {
+	snippet, err := s.SnippetStore.Burn(slug)
+	if err != nil {
+		return nil, err
+	}
+	s.logError(s.index.Remove(snippet.ID))
+	return snippet, nil
+}
+
+func (s *indexedSnippets) DeleteExpired(limit int, archive bool) (int, error) {
+	n, err := s.SnippetStore.DeleteExpired(limit, archive)
+	if err != nil {
+		return n, err
+	}
+	s.logError(s.index.RemoveExpired(time.Now()))
+	return n, nil
+}
+
+// reindex adds the snippet read back after a write to the index.
+func (s *indexedSnippets) reindex(snippet *models.Snippet, err error) {
+	if err != nil {
+		s.logError(err)
+		return
+	}
+	s.logError(s.index.Add(searchDocument(snippet)))
+}
+
+func (s *indexedSnippets) logError(err error) {
+	if err != nil {
+		s.errorLog.Printf("updating the search index: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+	"github.com/Yusufdot101/snippetbox/internal/search"
+)
+
+// found returns the IDs of the snippets the user with the given id finds by
+// searching for text.
+func found(t *testing.T, app *application, userID int, text string) []int {
+	t.Helper()
+	results, err := app.searchIndex.Search(search.Query{Text: text, UserID: userID, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, hit := range results.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestSearchIndexFollowsWrites(t *testing.T) {
+	app := newTestApplication(t)
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+
+	slug := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "nginx config", Content: "listen 80"})
+	snippet, err := app.snippets.GetBySlug(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := found(t, app, 0, "nginx"); !slices.Equal(got, []int{snippet.ID}) {
+		t.Fatalf("after insert, nginx found %v; want [%d]", got, snippet.ID)
+	}
+
+	snippet.Title, snippet.Content = "apache config", "Listen 8080"
+	if err := app.snippets.Update(snippet, aliceID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := found(t, app, 0, "nginx"); got != nil {
+		t.Errorf("after update, nginx found %v; want nothing", got)
+	}
+	if got := found(t, app, 0, "apache 8080"); !slices.Equal(got, []int{snippet.ID}) {
+		t.Errorf("after update, apache found %v; want [%d]", got, snippet.ID)
+	}
+
+	snippet.Visibility = models.VisibilityPrivate
+	if err := app.snippets.Update(snippet, aliceID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := found(t, app, 0, "apache"); got != nil {
+		t.Errorf("after making it private, anyone found %v; want nothing", got)
+	}
+	if got := found(t, app, aliceID, "apache"); !slices.Equal(got, []int{snippet.ID}) {
+		t.Errorf("after making it private, its owner found %v; want [%d]", got, snippet.ID)
+	}
+
+	if err := app.snippets.Delete(snippet.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := found(t, app, aliceID, "apache"); got != nil {
+		t.Errorf("after delete, apache found %v; want nothing", got)
+	}
+}
+
+func TestSearchIndexLeavesOutWhatOthersMayNotFind(t *testing.T) {
+	app := newTestApplication(t)
+	aliceID := insertUser(t, app, "Alice", "alice@example.com")
+
+	burn := insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "burn", Content: "secret", BurnAfterReading: true})
+	insertSnippet(t, app, &models.Snippet{UserID: aliceID, Title: "unlisted", Content: "secret", Visibility: models.VisibilityUnlisted})
+
+	protected := &models.Snippet{UserID: aliceID, Title: "protected", Content: "secret"}
+	if err := protected.SetPassphrase("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	insertSnippet(t, app, protected)
+
+	if got := found(t, app, 0, "secret"); got != nil {
+		t.Errorf("anyone found %v; want nothing", got)
+	}
+	// the owner finds their burn after reading and unlisted snippets, but
+	// content behind a passphrase isn't indexed at all
+	if got := found(t, app, aliceID, "secret"); len(got) != 2 {
+		t.Errorf("the owner found %v; want 2 snippets", got)
+	}
+	if got := found(t, app, aliceID, "protected"); len(got) != 1 {
+		t.Errorf("the owner found %v by title; want 1 snippet", got)
+	}
+
+	if _, err := app.snippets.Burn(burn); err != nil {
+		t.Fatal(err)
+	}
+	if got := found(t, app, aliceID, "burn"); got != nil {
+		t.Errorf("after burning, burn found %v; want nothing", got)
+	}
+}
+
+func TestBuildSearchIndex(t *testing.T) {
+	memory := models.NewMemoryModels()
+	if err := memory.Users.Insert("Alice", "alice@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = indexBatch + 3
+	for range n {
+		_, err := memory.Snippets.Insert(&models.Snippet{
+			UserID:     1,
+			Title:      "note",
+			Content:    "text",
+			Visibility: models.VisibilityPublic,
+		}, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index := search.NewMemory()
+	indexed, err := buildSearchIndex(memory.Snippets, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed != n {
+		t.Errorf("indexed %d snippets; want %d", indexed, n)
+	}
+
+	results, err := index.Search(search.Query{Text: "note", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Total != n {
+		t.Errorf("found %d snippets; want %d", results.Total, n)
+	}
+}
@@ -1,40 +1,81 @@
 package main
 
 import (
+	"html/template"
 	"net/http"
 	"path/filepath"
-	"text/template"
 	"time"
 
+	"github.com/Yusufdot101/snippetbox/internal/diff"
+	"github.com/Yusufdot101/snippetbox/internal/expiry"
+	"github.com/Yusufdot101/snippetbox/internal/highlight"
 	"github.com/Yusufdot101/snippetbox/internal/models"
 	"github.com/justinas/nosurf"
 )
 
 type templateData struct {
-	CurrentYear     int
-	Snippet         *models.Snippet
-	Snippets        []*models.Snippet
-	Form            any
-	Flash           string
-	IsAuthenticated bool
-	CSRFToken       string
+	CurrentYear         int
+	Snippet             *models.Snippet
+	Snippets            []*models.Snippet
+	Markdown            template.HTML
+	ShowSource          bool
+	DiffFrom            *models.Snippet
+	DiffTo              *models.Snippet
+	Diff                []diff.Hunk
+	Tokens              []*models.Token
+	Reminders           []reminder
+	Search              *searchPage
+	UnseenNotifications int
+	Expiry              expiry.Policy
+	NewToken            string
+	Form                any
+	Flash               string
+	IsAuthenticated     bool
+	AuthenticatedUserID int
+	CSRFToken           string
 }
 
 func humanDate(t time.Time) string {
 	return t.Format("2 Jan 2006 at 15:04")
 }
 
+func sub(a, b int) int {
+	return a - b
+}
+
+// languages returns the choices for the language dropdown.
+func languages() []highlight.Language {
+	return highlight.Languages
+}
+
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":     humanDate,
+	"sub":           sub,
+	"highlight":     highlight.HTML,
+	"languageLabel": highlight.Label,
+	"languages":     languages,
 }
 
 func (app *application) newTemplateData(r *http.Request) *templateData {
-	return &templateData{
-		CurrentYear:     time.Now().Year(),
-		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
-		IsAuthenticated: app.isAuthenticated(r),
-		CSRFToken:       nosurf.Token(r),
+	data := &templateData{
+		CurrentYear:         time.Now().Year(),
+		Flash:               app.sessionManager.PopString(r.Context(), "flash"),
+		IsAuthenticated:     app.isAuthenticated(r),
+		AuthenticatedUserID: app.authenticatedUserID(r),
+		CSRFToken:           nosurf.Token(r),
+		Expiry:              app.expiryPolicy,
 	}
+
+	// the count in the navigation bar isn't worth failing the page over
+	if data.IsAuthenticated {
+		unseen, err := app.notifications.Unseen(data.AuthenticatedUserID)
+		if err != nil {
+			app.errorLog.Print(err)
+		}
+		data.UnseenNotifications = unseen
+	}
+
+	return data
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+func TestHostileSnippetIsEscaped(t *testing.T) {
+	app := newTestApplication(t)
+
+	err := app.users.Insert("Mallory", "mallory@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostile := `<script>alert("pwned")</script>`
+	slug, err := app.snippets.Insert(&models.Snippet{
+		UserID:     1,
+		Title:      hostile,
+		Content:    hostile + `<img src=x onerror="alert(1)">`,
+		Visibility: models.VisibilityPublic,
+	}, time.Now().Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	escaped := "&lt;script&gt;alert(&#34;pwned&#34;)&lt;/script&gt;"
+	tests := []struct {
+		path        string
+		wantEscaped string
+	}{
+		{"/", escaped},
+		{"/s/" + slug, escaped},
+		// search results mark the words searched for, in the title too
+		{"/search?q=pwned", "&lt;script&gt;alert(&#34;<mark>pwned</mark>&#34;)&lt;/script&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d; want %d", rr.Code, http.StatusOK)
+			}
+
+			body := rr.Body.String()
+			if strings.Contains(body, "<script>alert") || strings.Contains(body, "<img src=x") {
+				t.Errorf("snippet was rendered unescaped:\n%s", body)
+			}
+			if !strings.Contains(body, tt.wantEscaped) {
+				t.Errorf("escaped snippet title not found in body:\n%s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/expiry"
+	"github.com/Yusufdot101/snippetbox/internal/models"
+	"github.com/Yusufdot101/snippetbox/internal/search"
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+)
+
+// newTestApplication returns an application backed by the in-memory stores.
+// The templates are read relative to the repository root, so it changes
+// into it for the duration of the test.
+func newTestApplication(t *testing.T) *application {
+	t.Chdir("../..")
+
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		errorLog:       log.New(io.Discard, "", 0),
+		infoLog:        log.New(io.Discard, "", 0),
+		templateCache:  templateCache,
+		formDecoder:    form.NewDecoder(),
+		sessionManager: scs.New(),
+		unlockLimiter:  newFailureLimiter(5, 15*time.Minute),
+		expiryPolicy:   expiry.Policy{Max: 365 * 24 * time.Hour, AllowNever: true},
+		linkKey:        []byte("test link secret"),
+	}
+	memory := models.NewMemoryModels()
+	app.snippets, app.users, app.tokens = memory.Snippets, memory.Users, memory.Tokens
+	app.notifications = memory.Notifications
+	app.searchIndex = search.NewMemory()
+	app.snippets = &indexedSnippets{SnippetStore: app.snippets, index: app.searchIndex, errorLog: app.errorLog}
+
+	return app
+}
+
+// testServer serves an application's routes over TLS, like the real server,
+// so that the session and CSRF cookies, which are marked secure, are kept.
+type testServer struct {
+	*httptest.Server
+}
+
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	ts := httptest.NewTLSServer(h)
+	t.Cleanup(ts.Close)
+	return &testServer{ts}
+}
+
+// browser visits a test server with cookies of its own, so that tests can
+// act as several users at once. It doesn't follow redirects.
+type browser struct {
+	ts     *testServer
+	client *http.Client
+	// token, if set, is sent as a bearer token.
+	token string
+}
+
+func (ts *testServer) newBrowser(t *testing.T) *browser {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{
+		Transport: ts.Client().Transport,
+		Jar:       jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &browser{ts: ts, client: client}
+}
+
+// do sends a request to path and returns the status code, headers and body
+// of the response. Requests come from the test server's own origin, as they
+// would from one of its pages.
+func (b *browser) do(t *testing.T, method, path string, body io.Reader) (int, http.Header, string) {
+	req, err := http.NewRequest(method, b.ts.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return b.send(t, req)
+}
+
+// postJSON posts v, encoded as JSON, to path.
+func (b *browser) postJSON(t *testing.T, path string, v any) (int, http.Header, string) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, b.ts.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return b.send(t, req)
+}
+
+// send sends req, adding the browser's origin and token.
+func (b *browser) send(t *testing.T, req *http.Request) (int, http.Header, string) {
+	req.Header.Set("Origin", b.ts.URL)
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	rs, err := b.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	content, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs.StatusCode, rs.Header, string(content)
+}
+
+func (b *browser) get(t *testing.T, path string) (int, http.Header, string) {
+	return b.do(t, http.MethodGet, path, nil)
+}
+
+// postForm posts form to path along with the CSRF token from the page at
+// path.
+func (b *browser) postForm(t *testing.T, path string, form url.Values) (int, http.Header, string) {
+	form.Set("csrf_token", b.csrfToken(t, path))
+	return b.do(t, http.MethodPost, path, strings.NewReader(form.Encode()))
+}
+
+var csrfTokenRX = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// csrfToken returns the CSRF token from the page at path, or from the login
+// page if path can't be read with a GET.
+func (b *browser) csrfToken(t *testing.T, path string) string {
+	_, _, body := b.get(t, path)
+	m := csrfTokenRX.FindStringSubmatch(body)
+	if m == nil {
+		_, _, body = b.get(t, "/users/login")
+		if m = csrfTokenRX.FindStringSubmatch(body); m == nil {
+			t.Fatalf("no CSRF token found on %s", path)
+		}
+	}
+	return html.UnescapeString(m[1])
+}
+
+// login logs the browser in as the user with the given email, whose
+// password is "password123".
+func (b *browser) login(t *testing.T, email string) {
+	code, _, _ := b.postForm(t, "/users/login", url.Values{"email": {email}, "password": {"password123"}})
+	if code != http.StatusSeeOther {
+		t.Fatalf("logging in as %s: got status %d; want %d", email, code, http.StatusSeeOther)
+	}
+}
+
+// insertUser adds a user with the password "password123" and returns their
+// id.
+func insertUser(t *testing.T, app *application, name, email string) int {
+	if err := app.users.Insert(name, email, "password123"); err != nil {
+		t.Fatal(err)
+	}
+	id, err := app.users.Authenticate(email, "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// insertSnippet adds snippet, kept for a week, and returns its slug.
+func insertSnippet(t *testing.T, app *application, snippet *models.Snippet) string {
+	if snippet.Visibility == "" {
+		snippet.Visibility = models.VisibilityPublic
+	}
+	slug, err := app.snippets.Insert(snippet, time.Now().Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slug
+}
+
+// insertToken creates an API token for the user and returns it in plain text.
+func insertToken(t *testing.T, app *application, userID int) string {
+	token, err := app.tokens.Insert(userID, "test", time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
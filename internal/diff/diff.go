@@ -0,0 +1,283 @@
+// Package diff computes line based unified diffs between two texts.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Prefix returns the marker unified diffs put in front of the line.
+func (l Line) Prefix() string {
+	switch l.Op {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Class returns a CSS class name describing the line.
+func (l Line) Class() string {
+	switch l.Op {
+	case Delete:
+		return "diff-delete"
+	case Insert:
+		return "diff-insert"
+	default:
+		return "diff-equal"
+	}
+}
+
+// Hunk is a run of changed lines together with their surrounding context.
+// FromLine and ToLine are 1-based, as in the "@@ -l,s +l,s @@" header.
+type Hunk struct {
+	FromLine  int
+	FromCount int
+	ToLine    int
+	ToCount   int
+	Lines     []Line
+}
+
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.FromLine, h.FromCount, h.ToLine, h.ToCount)
+}
+
+// Unified returns the hunks needed to turn from into to, each padded with up
+// to context unchanged lines on either side. Identical inputs yield no hunks.
+func Unified(from, to string, context int) []Hunk {
+	lines := Lines(from, to)
+
+	var hunks []Hunk
+	fromLine, toLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			fromLine++
+			toLine++
+			i++
+			continue
+		}
+
+		// Back up to include the leading context.
+		start := i
+		for start > 0 && i-start < context && lines[start-1].Op == Equal {
+			start--
+		}
+		hunk := Hunk{
+			FromLine: fromLine - (i - start),
+			ToLine:   toLine - (i - start),
+		}
+
+		// Walk forward until we see more than 2*context unchanged lines in a
+		// row, which would split this change into two hunks.
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == Equal {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end += min(run-end, context)
+				break
+			}
+			end = run
+		}
+
+		hunk.Lines = lines[start:end]
+		for _, line := range hunk.Lines {
+			if line.Op != Insert {
+				hunk.FromCount++
+			}
+			if line.Op != Delete {
+				hunk.ToCount++
+			}
+		}
+		hunks = append(hunks, hunk)
+
+		for _, line := range lines[i:end] {
+			if line.Op != Insert {
+				fromLine++
+			}
+			if line.Op != Delete {
+				toLine++
+			}
+		}
+		i = end
+	}
+
+	return hunks
+}
+
+// Lines returns the full edit script between from and to, one entry per line.
+func Lines(from, to string) []Line {
+	a, b := split(from), split(to)
+	return appendLines(make([]Line, 0, max(len(a), len(b))), a, b)
+}
+
+// maxBisectCost bounds how far bisect searches for where two halves of an
+// edit script meet. Beyond it the texts are so different that finding the
+// shortest script isn't worth the time, which grows with the square of the
+// number of edits, and bisect settles for replacing one side with the other.
+const maxBisectCost = 1000
+
+// appendLines appends the edit script between a and b to lines. It uses the
+// linear space variant of Myers' O(ND) algorithm: bisect finds a point on an
+// optimal path halfway through it and the two halves are diffed on their own,
+// so however different the texts, memory stays proportional to their length.
+func appendLines(lines []Line, a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	if len(a) == 0 || len(b) == 0 {
+		for _, text := range a {
+			lines = append(lines, Line{Op: Delete, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, Line{Op: Insert, Text: text})
+		}
+	} else {
+		x, y := bisect(a, b)
+		lines = appendLines(lines, a[:x], b[:y])
+		lines = appendLines(lines, a[x:], b[y:])
+	}
+
+	for _, text := range common {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	return lines
+}
+
+// bisect returns a point (x, y) on a shortest edit path from a to b that
+// splits it into two shorter ones. It searches forwards from the start and
+// backwards from the end at the same time until the two searches meet.
+// forward[k] holds the furthest x reached on diagonal k = x - y, and
+// backward[k] the same for the backward search, with x and y counted from
+// the ends of a and b. Diagonals that have run off the edit graph are no
+// longer searched.
+func bisect(a, b []string) (x, y int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+
+	forward := make([]int, size)
+	backward := make([]int, size)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// When delta is odd the searches meet while searching forwards,
+	// otherwise while searching backwards.
+	odd := delta%2 != 0
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for d := 0; d < min(maxD, maxBisectCost); d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case odd:
+				j := offset + delta - k
+				if j >= 0 && j < size && backward[j] != -1 && x >= n-backward[j] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[i] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !odd:
+				j := offset + delta - k
+				if j >= 0 && j < size && forward[j] != -1 {
+					fx := forward[j]
+					if fx >= n-x {
+						return fx, offset + fx - j
+					}
+				}
+			}
+		}
+	}
+
+	// Only reached if nothing is in common, when deleting all of a and
+	// inserting all of b is as short as any other path, or if the search
+	// gave up.
+	return n, 0
+}
+
+// split breaks s into lines, ignoring a single trailing newline and
+// normalising Windows line endings the browser submits from a textarea.
+func split(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
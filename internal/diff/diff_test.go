@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apply rebuilds both sides of an edit script.
+func apply(lines []Line) (from, to []string) {
+	for _, line := range lines {
+		if line.Op != Insert {
+			from = append(from, line.Text)
+		}
+		if line.Op != Delete {
+			to = append(to, line.Text)
+		}
+	}
+	return from, to
+}
+
+// shortestEdit returns the length of a shortest edit script between a and b,
+// computed the slow way from their longest common subsequence.
+func shortestEdit(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func TestLinesIsAShortestEditScript(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for range 2000 {
+		from, to := randomText(), randomText()
+		lines := Lines(from, to)
+
+		gotFrom, gotTo := apply(lines)
+		if strings.Join(gotFrom, "\n") != from || strings.Join(gotTo, "\n") != to {
+			t.Fatalf("script for %q -> %q doesn't rebuild its inputs: %v", from, to, lines)
+		}
+
+		edits := 0
+		for _, line := range lines {
+			if line.Op != Equal {
+				edits++
+			}
+		}
+		if want := shortestEdit(split(from), split(to)); edits != want {
+			t.Fatalf("script for %q -> %q has %d edits; want %d", from, to, edits, want)
+		}
+	}
+}
+
+func TestLinesOfLargeUnrelatedTexts(t *testing.T) {
+	var from, to strings.Builder
+	for i := range 20000 {
+		from.WriteString("from " + strconv.Itoa(i) + "\n")
+		to.WriteString("to " + strconv.Itoa(i) + "\n")
+	}
+
+	start := time.Now()
+	lines := Lines(from.String(), to.String())
+	if len(lines) != 40000 {
+		t.Errorf("got %d lines; want 40000", len(lines))
+	}
+	t.Logf("diffed in %s", time.Since(start))
+}
+
+func TestLinesOfLargeSimilarTexts(t *testing.T) {
+	var from, to strings.Builder
+	for i := range 20000 {
+		line := "line " + strconv.Itoa(i) + "\n"
+		from.WriteString(line)
+		if i%200 == 0 {
+			line = "changed " + strconv.Itoa(i) + "\n"
+		}
+		to.WriteString(line)
+	}
+
+	edits := 0
+	for _, line := range Lines(from.String(), to.String()) {
+		if line.Op != Equal {
+			edits++
+		}
+	}
+	if edits != 200 {
+		t.Errorf("got %d edits; want 200", edits)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	to := "a\nb\nC\nd\ne\nf\ng\nh\nI\n"
+
+	hunks := Unified(from, to, 1)
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks; want 2", len(hunks))
+	}
+	if got, want := hunks[0].Header(), "@@ -2,3 +2,3 @@"; got != want {
+		t.Errorf("first hunk header %q; want %q", got, want)
+	}
+	if got, want := hunks[1].Header(), "@@ -8,2 +8,2 @@"; got != want {
+		t.Errorf("second hunk header %q; want %q", got, want)
+	}
+	if Unified(from, from, 3) != nil {
+		t.Error("identical texts produced hunks")
+	}
+}
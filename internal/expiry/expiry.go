@@ -0,0 +1,162 @@
+// Package expiry parses how long a snippet should be kept, as chosen from a
+// list of presets or typed in, and checks it against the limits set by the
+// site's administrator.
+//
+// Lifetimes are written as a number followed by a unit, m for minutes, h for
+// hours, d for days or w for weeks, such as "90m", "3h" or "30d", or in any
+// form time.ParseDuration accepts, such as "1h30m". A number without a unit,
+// such as "7", is a number of days, as lifetimes used to be given. "never"
+// asks for a snippet to be kept until it is deleted.
+package expiry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Never is the value that asks for a snippet to be kept until it is deleted.
+const Never = "never"
+
+// Min is the shortest lifetime a snippet can be given.
+const Min = 10 * time.Minute
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// unitRX matches lifetimes written with one of the units Parse adds to those
+// of time.ParseDuration, or with no unit at all for days.
+var unitRX = regexp.MustCompile(`^(\d{1,6})\s*([mhdw]?)$`)
+
+// Parse reads a lifetime. It returns never set, and a zero duration, for
+// "never".
+func Parse(value string) (d time.Duration, never bool, err error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == Never {
+		return 0, true, nil
+	}
+
+	if m := unitRX.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.ParseInt(m[1], 10, 64)
+		unit := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": day, "": day, "w": week}[m[2]]
+		if n > math.MaxInt64/int64(unit) {
+			return 0, false, fmt.Errorf("%q is too long a lifetime", value)
+		}
+		return time.Duration(n) * unit, false, nil
+	}
+
+	d, err = time.ParseDuration(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("%q is not a lifetime such as 3h or 30d", value)
+	}
+	return d, false, nil
+}
+
+// Format writes d in the largest unit that divides it, such as "3 hours" or
+// "30 days".
+func Format(d time.Duration) string {
+	n, unit := int64(d/time.Minute), "minute"
+	switch {
+	case d%day == 0:
+		n, unit = int64(d/day), "day"
+	case d%time.Hour == 0:
+		n, unit = int64(d/time.Hour), "hour"
+	}
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.FormatInt(n, 10) + " " + unit + "s"
+}
+
+// Preset is one of the lifetimes offered on the snippet form.
+type Preset struct {
+	Value string
+	Label string
+}
+
+// presets are offered in this order, unless the policy rules them out.
+var presets = []Preset{
+	{"10m", "Ten Minutes"},
+	{"1h", "One Hour"},
+	{"1d", "One Day"},
+	{"7d", "One Week"},
+	{"30d", "One Month"},
+	{"365d", "One Year"},
+	{Never, "Never"},
+}
+
+// Policy is the range of lifetimes snippets may be given.
+type Policy struct {
+	// Max is the longest lifetime allowed.
+	Max time.Duration
+	// AllowNever is whether snippets may be kept until they are deleted.
+	AllowNever bool
+}
+
+// Presets returns the presets the policy allows.
+func (p Policy) Presets() []Preset {
+	allowed := make([]Preset, 0, len(presets))
+	for _, preset := range presets {
+		if p.Check(preset.Value) == nil {
+			allowed = append(allowed, preset)
+		}
+	}
+	return allowed
+}
+
+// Default returns the lifetime preselected on the snippet form: one year, or
+// the longest the policy allows if that is shorter.
+func (p Policy) Default() string {
+	value := "365d"
+	for _, preset := range p.Presets() {
+		if preset.Value == "365d" {
+			return preset.Value
+		}
+		if preset.Value != Never {
+			value = preset.Value
+		}
+	}
+	return value
+}
+
+// Check returns an error if value isn't a lifetime the policy allows.
+func (p Policy) Check(value string) error {
+	_, err := p.Expires(value, time.Now())
+	return err
+}
+
+// Expires returns when a snippet given the lifetime value at now expires. It
+// returns the zero time for "never".
+func (p Policy) Expires(value string, now time.Time) (time.Time, error) {
+	d, never, err := Parse(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if never {
+		if !p.AllowNever {
+			return time.Time{}, errors.New("snippets can't be kept forever")
+		}
+		return time.Time{}, nil
+	}
+	if d < Min || d > p.Max {
+		return time.Time{}, fmt.Errorf("lifetime must be %s", p)
+	}
+	return now.Add(d), nil
+}
+
+// String describes the lifetimes the policy allows, such as "between 10
+// minutes and 365 days, or never".
+func (p Policy) String() string {
+	s := "between " + Format(Min) + " and " + Format(p.Max)
+	if p.AllowNever {
+		s += ", or never"
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package expiry
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value     string
+		want      time.Duration
+		wantNever bool
+		wantErr   bool
+	}{
+		{value: "10m", want: 10 * time.Minute},
+		{value: "3h", want: 3 * time.Hour},
+		{value: "30d", want: 30 * day},
+		{value: "2w", want: 2 * week},
+		{value: " 30 D ", want: 30 * day},
+		{value: "7", want: 7 * day},
+		{value: "365", want: 365 * day},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "never", wantNever: true},
+		{value: "Never", wantNever: true},
+		{value: "106751d", want: 106751 * day},
+		// longer than a time.Duration can hold, rather than wrapped around
+		{value: "213504d", wantErr: true},
+		{value: "213504", wantErr: true},
+		{value: "999999w", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "-7", wantErr: true},
+		{value: "7y", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			d, never, err := Parse(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v; want error %t", err, tt.wantErr)
+			}
+			if d != tt.want || never != tt.wantNever {
+				t.Errorf("got %s, never %t; want %s, never %t", d, never, tt.want, tt.wantNever)
+			}
+		})
+	}
+}
+
+func TestPolicyDefault(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"a year allowed", Policy{Max: 365 * day, AllowNever: true}, "365d"},
+		{"more than a year allowed", Policy{Max: 1000 * day}, "365d"},
+		{"a month at most", Policy{Max: 30 * day, AllowNever: true}, "30d"},
+		{"between presets", Policy{Max: 100 * day}, "30d"},
+		{"an hour at most", Policy{Max: time.Hour}, "1h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Default(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyExpires(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := Policy{Max: 30 * day}
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "10m", want: now.Add(10 * time.Minute)},
+		{value: "7", want: now.Add(7 * day)},
+		{value: "30d", want: now.Add(30 * day)},
+		{value: "5m", wantErr: true},
+		{value: "31d", wantErr: true},
+		{value: "never", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := policy.Expires(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v; want error %t", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+
+	never, err := Policy{Max: 30 * day, AllowNever: true}.Expires("never", now)
+	if err != nil || !never.IsZero() {
+		t.Errorf("got %s, %v for never; want the zero time", never, err)
+	}
+}
+
+func TestPolicyPresets(t *testing.T) {
+	var got []string
+	for _, preset := range (Policy{Max: 7 * day}).Presets() {
+		got = append(got, preset.Value)
+	}
+
+	want := []string{"10m", "1h", "1d", "7d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+// Package highlight renders snippet content as syntax highlighted HTML.
+//
+// The output only uses CSS classes, never inline styles, so it can be themed
+// from main.css and works under the Content-Security-Policy set by
+// secureHeader.
+package highlight
+
+import (
+	"encoding/json"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+type Language struct {
+	Value string
+	Label string
+}
+
+// Languages are the choices offered when creating a snippet, in the order
+// they are shown. The values are chroma lexer aliases.
+var Languages = []Language{
+	{"bash", "Bash"},
+	{"c", "C"},
+	{"cpp", "C++"},
+	{"css", "CSS"},
+	{"diff", "Diff"},
+	{"docker", "Dockerfile"},
+	{"go", "Go"},
+	{"html", "HTML"},
+	{"ini", "INI"},
+	{"java", "Java"},
+	{"javascript", "JavaScript"},
+	{"json", "JSON"},
+	{"markdown", "Markdown"},
+	{"nginx", "Nginx"},
+	{"php", "PHP"},
+	{"python", "Python"},
+	{"ruby", "Ruby"},
+	{"rust", "Rust"},
+	{"sql", "SQL"},
+	{"toml", "TOML"},
+	{"typescript", "TypeScript"},
+	{"xml", "XML"},
+	{"yaml", "YAML"},
+	{"text", "Plain text"},
+}
+
+// Values returns the value of every language in Languages.
+func Values() []string {
+	values := make([]string, len(Languages))
+	for i, language := range Languages {
+		values[i] = language.Value
+	}
+	return values
+}
+
+// Label returns the human readable name of a language value, or "" if it
+// isn't known.
+func Label(value string) string {
+	for _, language := range Languages {
+		if language.Value == value {
+			return language.Label
+		}
+	}
+	if lexer := lexers.Get(value); lexer != nil {
+		return lexer.Config().Name
+	}
+	return ""
+}
+
+// yamlKeyRX matches a "key: value" or "key:" line, the most common shape of
+// a YAML document.
+var yamlKeyRX = regexp.MustCompile(`^[\w.-]+:(\s|$)`)
+
+// sqlRX matches the statements most SQL snippets start with.
+var sqlRX = regexp.MustCompile(`(?i)^(select|insert|update|delete|create|alter|drop|with)\s`)
+
+// Detect guesses the language of content and returns its value, or "text"
+// if it can't tell. A few cheap checks cover common formats first, then
+// chroma's analysers for the offered languages, which recognise things like
+// shebangs and doctypes, get a say. Chroma's other lexers are left out as
+// they match far too eagerly.
+func Detect(content string) string {
+	trimmed := strings.TrimSpace(content)
+	firstLine, _, _ := strings.Cut(trimmed, "\n")
+
+	switch {
+	case (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)):
+		return "json"
+	case strings.HasPrefix(trimmed, "<?php"):
+		return "php"
+	case strings.HasPrefix(trimmed, "<?xml"):
+		return "xml"
+	case strings.HasPrefix(strings.ToLower(trimmed), "<!doctype html") || strings.HasPrefix(trimmed, "<html"):
+		return "html"
+	case strings.HasPrefix(firstLine, "#!") && strings.Contains(firstLine, "python"):
+		return "python"
+	case strings.HasPrefix(firstLine, "#!") && strings.Contains(firstLine, "node"):
+		return "javascript"
+	case strings.HasPrefix(firstLine, "diff ") || strings.HasPrefix(firstLine, "--- "):
+		return "diff"
+	case strings.HasPrefix(trimmed, "package ") && strings.Contains(trimmed, "func "):
+		return "go"
+	case sqlRX.MatchString(trimmed):
+		return "sql"
+	case firstLine == "---" || yamlKeyRX.MatchString(firstLine):
+		return "yaml"
+	}
+
+	best, bestScore := "text", float32(0)
+	for _, language := range Languages {
+		analyser, ok := lexers.Get(language.Value).(chroma.Analyser)
+		if !ok {
+			continue
+		}
+		if score := analyser.AnalyseText(content); score > bestScore {
+			best, bestScore = language.Value, score
+		}
+	}
+	return best
+}
+
+var formatter = html.New(html.WithClasses(true), html.TabWidth(4))
+
+// HTML returns content highlighted as language inside a <pre class="chroma">
+// block. Unknown languages are rendered as plain text. The content is escaped
+// by the formatter, so the result is safe to put in a template.
+func HTML(content, language string) (template.HTML, error) {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	err = formatter.Format(&b, styles.Fallback, iterator)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(b.String()), nil
+}
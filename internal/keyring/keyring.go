@@ -0,0 +1,142 @@
+// Package keyring encrypts snippet fields before they are written to the
+// database, so that a copy of the database alone doesn't reveal them.
+//
+// Fields are sealed with AES-256-GCM under one of several named keys. The
+// name, or key ID, is stored next to the ciphertext, which lets keys be
+// rotated: new writes always use the current key, and the older keys are
+// kept to read rows that haven't been re-encrypted yet.
+//
+// Keys are written one per line, or separated by commas, as "id=key" where
+// key is 32 random bytes in base64, for example the output of
+// "openssl rand -base64 32". The first key is the current one. Blank lines
+// and lines starting with # are ignored.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// idRX matches the key IDs the database columns have room for.
+var idRX = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,32}$`)
+
+// Keyring holds the keys fields are sealed with. A nil *Keyring is valid and
+// leaves fields in plaintext, with an empty key ID.
+type Keyring struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// Load reads the keys in the file at path.
+func Load(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(b))
+}
+
+// Parse reads keys written in the format described in the package
+// documentation.
+func Parse(spec string) (*Keyring, error) {
+	k := &Keyring{aeads: make(map[string]cipher.AEAD)}
+
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == '\n' || r == ',' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(line, "=")
+		id = strings.TrimSpace(id)
+		if !ok || !idRX.MatchString(id) {
+			return nil, fmt.Errorf("keyring: %q is not an id=key pair", id)
+		}
+		if _, exists := k.aeads[id]; exists {
+			return nil, fmt.Errorf("keyring: key %q is listed twice", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("keyring: key %q should be 32 bytes in base64", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		k.aeads[id] = aead
+		if k.current == "" {
+			k.current = id
+		}
+	}
+
+	if k.current == "" {
+		return nil, errors.New("keyring: no keys given")
+	}
+	return k, nil
+}
+
+// Current returns the ID of the key new fields are sealed with, or "" for a
+// nil keyring.
+func (k *Keyring) Current() string {
+	if k == nil {
+		return ""
+	}
+	return k.current
+}
+
+// Seal encrypts plaintext with the current key and returns the key's ID along
+// with the ciphertext, base64 encoded. field names what is being sealed, such
+// as "content", and has to be given again to Open; it stops the ciphertext
+// of one field from being passed off as another's. A nil keyring returns
+// plaintext unchanged under the empty ID.
+func (k *Keyring) Seal(plaintext, field string) (keyID, sealed string, err error) {
+	if k == nil {
+		return "", plaintext, nil
+	}
+
+	aead := k.aeads[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", err
+	}
+	data := aead.Seal(nonce, nonce, []byte(plaintext), []byte(field))
+	return k.current, base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Open decrypts a field sealed with the key named keyID. An empty keyID means
+// the field was stored in plaintext and it is returned as is.
+func (k *Keyring) Open(keyID, sealed, field string) (string, error) {
+	if keyID == "" {
+		return sealed, nil
+	}
+	if k == nil {
+		return "", fmt.Errorf("keyring: %s is encrypted with key %q but no keys are configured", field, keyID)
+	}
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("keyring: %s is encrypted with unknown key %q", field, keyID)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("keyring: %s is malformed", field)
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("keyring: %s could not be decrypted with key %q", field, keyID)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,87 @@
+// Package mailer sends plain text email. The application only depends on the
+// Mailer interface, so how mail is delivered can be chosen at startup: SMTP
+// hands it to a mail server and Log writes it to a logger instead, which is
+// handy in development.
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTP sends messages through the mail server at Addr, a host:port pair,
+// from the address From, which may include a name as in
+// "Snippetbox <no-reply@example.com>". Username and Password, if set, are
+// used for PLAIN authentication, which net/smtp only allows over TLS or to
+// localhost.
+type SMTP struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m *SMTP) Send(msg Message) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	return smtp.SendMail(m.Addr, auth, from.Address, []string{msg.To}, m.format(msg))
+}
+
+// format writes msg out with the headers mail servers expect.
+func (m *SMTP) format(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header(m.From))
+	fmt.Fprintf(&b, "To: %s\r\n", header(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// header drops line breaks from a header value, so that a value can't add
+// headers of its own.
+func header(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// Log writes messages to Logger instead of sending them.
+type Log struct {
+	Logger *log.Logger
+}
+
+func (m *Log) Send(msg Message) error {
+	m.Logger.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
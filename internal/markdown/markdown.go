@@ -0,0 +1,90 @@
+// Package markdown renders Markdown snippets to HTML that is safe to put in
+// a page.
+//
+// GitHub flavoured Markdown is supported: headings, tables, strikethrough,
+// autolinks, task lists and fenced code blocks, which are highlighted with
+// the highlight package. Whatever the renderer produces is then passed
+// through an allow-list sanitizer, so raw HTML, scripts, inline styles and
+// event handlers never reach the browser.
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/Yusufdot101/snippetbox/internal/highlight"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+var converter = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(codeRenderer{}, 100)),
+	),
+)
+
+// classRX matches the class names the highlighter and the renderer emit.
+var classRX = regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)
+
+// policy is the allow-list every rendered document goes through. It starts
+// from bluemonday's policy for user generated content and adds the classes
+// used for highlighting and the disabled checkboxes of task lists.
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(classRX).OnElements("pre", "code", "span")
+	p.AllowElements("input")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// HTML renders source as sanitized HTML.
+func HTML(source string) (template.HTML, error) {
+	var b bytes.Buffer
+	if err := converter.Convert([]byte(source), &b); err != nil {
+		return "", err
+	}
+	return template.HTML(policy.SanitizeBytes(b.Bytes())), nil
+}
+
+// codeRenderer renders fenced code blocks with the highlight package instead
+// of as plain <pre><code> blocks.
+type codeRenderer struct{}
+
+func (codeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, renderFencedCode)
+}
+
+func renderFencedCode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	block := node.(*ast.FencedCodeBlock)
+
+	var content strings.Builder
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		content.Write(line.Value(source))
+	}
+
+	highlighted, err := highlight.HTML(content.String(), string(block.Language(source)))
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	if _, err := w.WriteString(string(highlighted)); err != nil {
+		return ast.WalkStop, err
+	}
+
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		want       []string
+		wantAbsent []string
+	}{
+		{
+			name:       "script element",
+			source:     "Hello <script>alert(1)</script>",
+			want:       []string{"Hello"},
+			wantAbsent: []string{"<script", "alert(1)</script>"},
+		},
+		{
+			name:       "raw HTML block",
+			source:     "<div onclick=\"alert(1)\">\n<iframe src=\"https://example.com\"></iframe>\n</div>",
+			wantAbsent: []string{"<div", "<iframe", "onclick"},
+		},
+		{
+			name:       "event handler attribute",
+			source:     `<img src="x" onerror="alert(1)">`,
+			wantAbsent: []string{"onerror", "<img"},
+		},
+		{
+			name:       "javascript link",
+			source:     "[click](javascript:alert(1))",
+			want:       []string{"click"},
+			wantAbsent: []string{"javascript:"},
+		},
+		{
+			name:       "javascript autolink",
+			source:     "<javascript:alert(1)>",
+			wantAbsent: []string{`href="javascript:`},
+		},
+		{
+			name:       "inline style",
+			source:     `<span style="position:fixed">x</span>`,
+			wantAbsent: []string{"style="},
+		},
+		{
+			name:   "link",
+			source: "[docs](https://example.com/docs)",
+			want:   []string{`<a href="https://example.com/docs"`, `rel="nofollow"`},
+		},
+		{
+			name:   "table",
+			source: "| a | b |\n|---|---|\n| 1 | 2 |",
+			want:   []string{"<table>", "<th>a</th>", "<td>2</td>"},
+		},
+		{
+			name:   "task list",
+			source: "- [x] done\n- [ ] to do",
+			want:   []string{`<input checked="" disabled="" type="checkbox"`, `<input disabled="" type="checkbox"`},
+		},
+		{
+			name:   "strikethrough",
+			source: "~~gone~~",
+			want:   []string{"<del>gone</del>"},
+		},
+		{
+			name:       "highlighted code block",
+			source:     "```go\nfunc main() {}\n```",
+			want:       []string{"<pre", `class="`, "main"},
+			wantAbsent: []string{"style="},
+		},
+		{
+			name:       "code block with HTML in it",
+			source:     "```\n<script>alert(1)</script>\n```",
+			want:       []string{"&lt;script&gt;"},
+			wantAbsent: []string{"<script>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := HTML(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := string(rendered)
+
+			for _, want := range tt.want {
+				if !strings.Contains(html, want) {
+					t.Errorf("%q not found in:\n%s", want, html)
+				}
+			}
+			for _, absent := range tt.wantAbsent {
+				if strings.Contains(html, absent) {
+					t.Errorf("%q found in:\n%s", absent, html)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Package migrations holds the versioned database schema for every supported
+// dialect and applies it. Each dialect has its own directory of numbered
+// files named like 0001_create_users.up.sql, with a matching .down.sql that
+// undoes it. Applied versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// Status describes a migration and whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	DB      *sql.DB
+	Dialect models.Dialect
+}
+
+const createVersionTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER NOT NULL PRIMARY KEY,
+		applied TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)
+`
+
+// Migrations returns every migration embedded for the dialect, oldest first.
+func (m *Migrator) Migrations() ([]Migration, error) {
+	dir := m.Dialect.String()
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		number, name, found := strings.Cut(base, "_")
+		if !ok || !found {
+			return nil, fmt.Errorf("migrations: badly named file %s", entry.Name())
+		}
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("migrations: badly named file %s", entry.Name())
+		}
+
+		contents, err := files.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		switch direction {
+		case "up":
+			migration.up = string(contents)
+		case "down":
+			migration.down = string(contents)
+		default:
+			return nil, fmt.Errorf("migrations: badly named file %s", entry.Name())
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Status returns every migration along with when it was applied, if it has
+// been.
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		at, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration in order and returns the ones it
+// applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+		err := m.run(status.up, fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%d)", status.Version))
+		if err != nil {
+			return done, fmt.Errorf("migrations: applying %04d_%s: %w", status.Version, status.Name, err)
+		}
+		done = append(done, status.Migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the n most recently applied migrations and returns the ones
+// it rolled back.
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, errors.New("migrations: must roll back at least one migration")
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < n; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+		err := m.run(status.down, fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %d", status.Version))
+		if err != nil {
+			return done, fmt.Errorf("migrations: rolling back %04d_%s: %w", status.Version, status.Name, err)
+		}
+		done = append(done, status.Migration)
+	}
+	return done, nil
+}
+
+// applied returns the versions recorded in schema_migrations, creating the
+// table first if needed.
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	_, err := m.DB.Exec(createVersionTable)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(`SELECT version, applied FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// run executes the statements in script followed by record in a single
+// transaction. MySQL commits DDL implicitly, so there a failure part way
+// through can leave the schema half migrated.
+func (m *Migrator) run(script, record string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// not every driver accepts several statements in one Exec, so run them
+	// one at a time. None of the migrations contain a semicolon inside a
+	// string or identifier.
+	for _, statement := range strings.Split(script, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(record); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,119 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+func TestEveryMigrationCanBeUndone(t *testing.T) {
+	for _, dialect := range []models.Dialect{models.MySQL, models.SQLite, models.Postgres} {
+		t.Run(dialect.String(), func(t *testing.T) {
+			all, err := (&Migrator{Dialect: dialect}).Migrations()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) == 0 {
+				t.Fatal("no migrations")
+			}
+			for i, migration := range all {
+				if migration.Version != i+1 {
+					t.Errorf("migration %d is numbered %04d", i+1, migration.Version)
+				}
+				if strings.TrimSpace(migration.up) == "" || strings.TrimSpace(migration.down) == "" {
+					t.Errorf("%04d_%s needs both an up and a down script", migration.Version, migration.Name)
+				}
+			}
+		})
+	}
+}
+
+// schema returns the statements that created every table and index in db,
+// apart from SQLite's own and the one recording the applied migrations.
+func schema(t *testing.T, db *sql.DB) map[string]string {
+	rows, err := db.Query(`
+		SELECT name, sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\' AND name != 'schema_migrations'
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	statements := make(map[string]string)
+	for rows.Next() {
+		var name, statement string
+		if err := rows.Scan(&name, &statement); err != nil {
+			t.Fatal(err)
+		}
+		statements[name] = statement
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return statements
+}
+
+func TestSQLiteUpDownUp(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "snippetbox.db")+"?_pragma=foreign_keys(1)&_time_format=sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &Migrator{DB: db, Dialect: models.SQLite}
+	all, err := m.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(all) {
+		t.Fatalf("applied %d migrations; want %d", len(applied), len(all))
+	}
+	migrated := schema(t, db)
+	if len(migrated) == 0 {
+		t.Fatal("migrating made no tables")
+	}
+
+	rolledBack, err := m.Down(len(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rolledBack) != len(all) {
+		t.Fatalf("rolled back %d migrations; want %d", len(rolledBack), len(all))
+	}
+	if left := schema(t, db); len(left) != 0 {
+		t.Errorf("rolling back left %v behind", left)
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	remigrated := schema(t, db)
+	if len(remigrated) != len(migrated) {
+		t.Errorf("migrating again made %d tables and indexes; want %d", len(remigrated), len(migrated))
+	}
+	for name, statement := range migrated {
+		if remigrated[name] != statement {
+			t.Errorf("migrating again made %s as\n%s\nwant\n%s", name, remigrated[name], statement)
+		}
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("%04d_%s isn't applied", status.Version, status.Name)
+		}
+	}
+}
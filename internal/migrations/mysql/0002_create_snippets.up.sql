@@ -0,0 +1,12 @@
+CREATE TABLE snippets (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL DEFAULT 1,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    CONSTRAINT snippets_fk_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_snippets_expires ON snippets (expires);
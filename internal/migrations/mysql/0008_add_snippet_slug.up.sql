@@ -0,0 +1,6 @@
+ALTER TABLE snippets ADD COLUMN slug VARCHAR(16) NULL;
+
+UPDATE snippets SET slug = REPLACE(REPLACE(TO_BASE64(RANDOM_BYTES(9)), '+', '-'), '/', '_');
+
+ALTER TABLE snippets MODIFY slug VARCHAR(16) NOT NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_slug UNIQUE (slug);
@@ -0,0 +1,2 @@
+DROP TABLE burned_snippets;
+ALTER TABLE snippets DROP COLUMN burn_after_reading;
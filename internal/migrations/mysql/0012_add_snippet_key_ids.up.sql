@@ -0,0 +1,8 @@
+ALTER TABLE snippets ADD COLUMN title_key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippets ADD COLUMN key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippets MODIFY title VARCHAR(1024) NOT NULL;
+ALTER TABLE snippets MODIFY content MEDIUMTEXT NOT NULL;
+ALTER TABLE snippet_revisions ADD COLUMN title_key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippet_revisions ADD COLUMN key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippet_revisions MODIFY title VARCHAR(1024) NOT NULL;
+ALTER TABLE snippet_revisions MODIFY content MEDIUMTEXT NOT NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE archived_snippets (
+    id INTEGER NOT NULL PRIMARY KEY,
+    slug VARCHAR(16) NOT NULL,
+    user_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    title_key_id VARCHAR(32) NOT NULL,
+    title VARCHAR(1024) NOT NULL,
+    key_id VARCHAR(32) NOT NULL,
+    content MEDIUMTEXT NOT NULL,
+    language VARCHAR(32) NOT NULL,
+    visibility VARCHAR(10) NOT NULL,
+    encrypted BOOLEAN NOT NULL,
+    burn_after_reading BOOLEAN NOT NULL,
+    passphrase_hash CHAR(60) NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    archived DATETIME NOT NULL
+);
@@ -0,0 +1,13 @@
+CREATE TABLE notifications (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    expires DATETIME NOT NULL,
+    created DATETIME NOT NULL,
+    seen BOOLEAN NOT NULL DEFAULT FALSE,
+    emailed BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT notifications_uc_expiry UNIQUE (snippet_id, expires),
+    CONSTRAINT notifications_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_notifications_user ON notifications (user_id);
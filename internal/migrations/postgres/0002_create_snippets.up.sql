@@ -0,0 +1,11 @@
+CREATE TABLE snippets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    revision INTEGER NOT NULL DEFAULT 1,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created TIMESTAMP NOT NULL,
+    expires TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_snippets_expires ON snippets (expires);
@@ -0,0 +1,10 @@
+CREATE TABLE tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    created TIMESTAMP NOT NULL,
+    last_used TIMESTAMP,
+    expires TIMESTAMP,
+    CONSTRAINT tokens_uc_hash UNIQUE (hash)
+);
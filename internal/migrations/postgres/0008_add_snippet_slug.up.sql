@@ -0,0 +1,13 @@
+ALTER TABLE snippets ADD COLUMN slug VARCHAR(16);
+
+-- Existing snippets get 9 random bytes, as many as the slugs the application
+-- makes, from gen_random_uuid(), which uses a cryptographically secure
+-- generator. Only the first 6 and the last 6 bytes of a version 4 UUID are
+-- all random, so the bytes are taken from two of them.
+UPDATE snippets SET slug = translate(encode(
+    substr(decode(replace(gen_random_uuid()::text, '-', ''), 'hex'), 1, 6) ||
+    substr(decode(replace(gen_random_uuid()::text, '-', ''), 'hex'), 11, 3),
+    'base64'), '+/', '-_');
+
+ALTER TABLE snippets ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_slug UNIQUE (slug);
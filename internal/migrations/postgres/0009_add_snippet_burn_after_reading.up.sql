@@ -0,0 +1,6 @@
+ALTER TABLE snippets ADD COLUMN burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE burned_snippets (
+    slug VARCHAR(16) PRIMARY KEY,
+    burned TIMESTAMP NOT NULL
+);
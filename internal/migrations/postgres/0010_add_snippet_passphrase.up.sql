@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN passphrase_hash CHAR(60) NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE snippets ADD COLUMN title_key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippets ADD COLUMN key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippets ALTER COLUMN title TYPE VARCHAR(1024);
+ALTER TABLE snippet_revisions ADD COLUMN title_key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippet_revisions ADD COLUMN key_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE snippet_revisions ALTER COLUMN title TYPE VARCHAR(1024);
@@ -0,0 +1,12 @@
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    snippet_id INTEGER NOT NULL,
+    expires TIMESTAMP NOT NULL,
+    created TIMESTAMP NOT NULL,
+    seen BOOLEAN NOT NULL DEFAULT FALSE,
+    emailed BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT notifications_uc_expiry UNIQUE (snippet_id, expires)
+);
+
+CREATE INDEX idx_notifications_user ON notifications (user_id);
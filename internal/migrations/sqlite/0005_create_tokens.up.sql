@@ -0,0 +1,10 @@
+CREATE TABLE tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    last_used DATETIME,
+    expires DATETIME,
+    CONSTRAINT tokens_uc_hash UNIQUE (hash)
+);
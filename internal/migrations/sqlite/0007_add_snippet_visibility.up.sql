@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
@@ -0,0 +1,6 @@
+ALTER TABLE snippets ADD COLUMN burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE burned_snippets (
+    slug TEXT PRIMARY KEY,
+    burned DATETIME NOT NULL
+);
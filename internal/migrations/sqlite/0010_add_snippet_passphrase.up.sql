@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN passphrase_hash TEXT;
@@ -0,0 +1,4 @@
+ALTER TABLE snippet_revisions DROP COLUMN key_id;
+ALTER TABLE snippet_revisions DROP COLUMN title_key_id;
+ALTER TABLE snippets DROP COLUMN key_id;
+ALTER TABLE snippets DROP COLUMN title_key_id;
@@ -0,0 +1,4 @@
+ALTER TABLE snippets ADD COLUMN title_key_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE snippets ADD COLUMN key_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE snippet_revisions ADD COLUMN title_key_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE snippet_revisions ADD COLUMN key_id TEXT NOT NULL DEFAULT '';
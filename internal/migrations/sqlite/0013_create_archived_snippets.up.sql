@@ -0,0 +1,18 @@
+CREATE TABLE archived_snippets (
+    id INTEGER NOT NULL PRIMARY KEY,
+    slug TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    title_key_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    key_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    language TEXT NOT NULL,
+    visibility TEXT NOT NULL,
+    encrypted BOOLEAN NOT NULL,
+    burn_after_reading BOOLEAN NOT NULL,
+    passphrase_hash TEXT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    archived DATETIME NOT NULL
+);
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect identifies which SQL database a SnippetModel or UserModel talks to.
+// The queries are written to be portable, with timestamps computed in Go
+// rather than by the database, so the dialect only matters where the drivers
+// disagree. The zero value is MySQL.
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	SQLite
+	Postgres
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case SQLite:
+		return "sqlite"
+	case Postgres:
+		return "postgres"
+	default:
+		return "mysql"
+	}
+}
+
+// isUniqueViolation reports whether err was caused by a duplicate value in a
+// unique column. MySQL and Postgres report the violated constraint by name,
+// SQLite by the table and column it covers, so callers pass both.
+func (d Dialect) isUniqueViolation(err error, constraint, column string) bool {
+	switch d {
+	case Postgres:
+		var pgError *pgconn.PgError
+		return errors.As(err, &pgError) && pgError.Code == "23505" &&
+			pgError.ConstraintName == constraint
+	case SQLite:
+		var sqliteError *sqlite.Error
+		return errors.As(err, &sqliteError) &&
+			sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE &&
+			strings.Contains(sqliteError.Error(), column)
+	default:
+		var mySQLError *mysql.MySQLError
+		return errors.As(err, &mySQLError) && mySQLError.Number == 1062 &&
+			strings.Contains(mySQLError.Message, constraint)
+	}
+}
+
+// rebind rewrites the ? placeholders the queries are written with into the
+// $1, $2, ... form Postgres expects. None of the queries contain a literal
+// question mark, so a plain scan is enough.
+func (d Dialect) rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// execer is the part of *sql.DB and *sql.Tx that insert and queries that can
+// run either inside or outside a transaction need.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// insert runs an INSERT statement and returns the id of the new row. The
+// Postgres drivers don't support LastInsertId, so there the id is read back
+// with RETURNING instead.
+func (d Dialect) insert(db execer, query string, args ...any) (int, error) {
+	if d == Postgres {
+		var id int
+		err := db.QueryRow(d.rebind(query)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := db.Exec(d.rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
@@ -8,4 +8,6 @@ var (
 	ErrInvaildCredentials = errors.New("models: invalid credentials")
 
 	ErrDuplicateEmail = errors.New("models: duplcate email")
+
+	ErrBurned = errors.New("models: snippet has been burned")
 )
@@ -0,0 +1,655 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryDB is the shared state behind MemorySnippetModel and MemoryUserModel,
+// so that snippets can be joined to the users who wrote them.
+type memoryDB struct {
+	mu            sync.RWMutex
+	users         map[int]*User
+	snippets      map[int]*Snippet
+	revisions     map[int][]*Snippet
+	tokens        map[int]*tokenRecord
+	burned        map[string]time.Time
+	archived      map[int]*Snippet
+	notifications map[int]*Notification
+	lastUserID    int
+	lastSnippetID int
+	lastTokenID   int
+
+	lastNotificationID int
+}
+
+// tokenRecord is a token as MemoryTokenModel stores it, alongside its hash.
+type tokenRecord struct {
+	Token
+	hash string
+}
+
+// MemorySnippetModel keeps snippets in memory. It is safe for concurrent use
+// and is intended for development and tests; everything is lost on exit.
+type MemorySnippetModel struct {
+	db *memoryDB
+}
+
+// MemoryUserModel keeps user accounts in memory alongside a
+// MemorySnippetModel.
+type MemoryUserModel struct {
+	db *memoryDB
+}
+
+// MemoryTokenModel keeps personal access tokens in memory alongside a
+// MemoryUserModel.
+type MemoryTokenModel struct {
+	db *memoryDB
+}
+
+// MemoryNotificationModel keeps notifications in memory alongside a
+// MemorySnippetModel.
+type MemoryNotificationModel struct {
+	db *memoryDB
+}
+
+// MemoryModels groups stores that share the same in-memory database.
+type MemoryModels struct {
+	Snippets      *MemorySnippetModel
+	Users         *MemoryUserModel
+	Tokens        *MemoryTokenModel
+	Notifications *MemoryNotificationModel
+}
+
+// NewMemoryModels returns empty stores that share the same in-memory
+// database.
+func NewMemoryModels() *MemoryModels {
+	db := &memoryDB{
+		users:     make(map[int]*User),
+		snippets:  make(map[int]*Snippet),
+		revisions: make(map[int][]*Snippet),
+		tokens:    make(map[int]*tokenRecord),
+		burned:    make(map[string]time.Time),
+		archived:  make(map[int]*Snippet),
+
+		notifications: make(map[int]*Notification),
+	}
+	return &MemoryModels{
+		Snippets:      &MemorySnippetModel{db: db},
+		Users:         &MemoryUserModel{db: db},
+		Tokens:        &MemoryTokenModel{db: db},
+		Notifications: &MemoryNotificationModel{db: db},
+	}
+}
+
+// withAuthor returns a copy of snippet with the author's name filled in. The
+// caller must hold db.mu.
+func (db *memoryDB) withAuthor(snippet *Snippet) *Snippet {
+	s := *snippet
+	if user, ok := db.users[s.UserID]; ok {
+		s.Author = user.Name
+	}
+	return &s
+}
+
+// live returns the snippet with the given id if it exists and hasn't expired.
+// The caller must hold db.mu.
+func (db *memoryDB) live(id int) (*Snippet, bool) {
+	snippet, ok := db.snippets[id]
+	if !ok || expired(snippet, time.Now().UTC()) {
+		return nil, false
+	}
+	return snippet, true
+}
+
+// remove deletes a snippet along with its revisions and notifications. The
+// caller must hold db.mu.
+func (db *memoryDB) remove(id int) {
+	delete(db.snippets, id)
+	delete(db.revisions, id)
+	for notificationID, notification := range db.notifications {
+		if notification.SnippetID == id {
+			delete(db.notifications, notificationID)
+		}
+	}
+}
+
+// utcOrZero returns t in UTC, keeping the zero time, which means never, as
+// it is.
+func utcOrZero(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC()
+}
+
+// expired reports whether snippet has expired by now.
+func expired(snippet *Snippet, now time.Time) bool {
+	return !snippet.Expires.IsZero() && !snippet.Expires.After(now)
+}
+
+// bySlug returns the snippet with the given slug, expired or not, or nil.
+// The caller must hold db.mu.
+func (db *memoryDB) bySlug(slug string) *Snippet {
+	for _, snippet := range db.snippets {
+		if snippet.Slug == slug {
+			return snippet
+		}
+	}
+	return nil
+}
+
+func (model *MemorySnippetModel) Insert(snippet *Snippet, expires time.Time) (string, error) {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	var slug string
+	for attempt := 1; ; attempt++ {
+		var err error
+		slug, err = newSlug()
+		if err != nil {
+			return "", err
+		}
+		if model.db.bySlug(slug) == nil {
+			break
+		}
+		if attempt == slugAttempts {
+			return "", errors.New("models: no unused slug found")
+		}
+	}
+
+	now := time.Now().UTC()
+	model.db.lastSnippetID++
+	stored := &Snippet{
+		ID:               model.db.lastSnippetID,
+		Slug:             slug,
+		Revision:         1,
+		UserID:           snippet.UserID,
+		Title:            snippet.Title,
+		Content:          snippet.Content,
+		Language:         snippet.Language,
+		Visibility:       snippet.Visibility,
+		Encrypted:        snippet.Encrypted,
+		BurnAfterReading: snippet.BurnAfterReading,
+		PassphraseHash:   snippet.PassphraseHash,
+		Created:          now,
+		Expires:          utcOrZero(expires),
+	}
+	model.db.snippets[stored.ID] = stored
+
+	revision := *stored
+	model.db.revisions[stored.ID] = []*Snippet{&revision}
+
+	return stored.Slug, nil
+}
+
+func (model *MemorySnippetModel) Update(snippet *Snippet, userID int, expires time.Time) error {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	id := snippet.ID
+	current, ok := model.db.snippets[id]
+	if !ok {
+		return ErrNoRecord
+	}
+
+	now := time.Now().UTC()
+	updated := *current
+	updated.Revision++
+	updated.Title = snippet.Title
+	updated.Content = snippet.Content
+	updated.Language = snippet.Language
+	updated.Visibility = snippet.Visibility
+	updated.Encrypted = snippet.Encrypted
+	updated.BurnAfterReading = snippet.BurnAfterReading
+	updated.PassphraseHash = snippet.PassphraseHash
+	updated.Expires = utcOrZero(expires)
+	model.db.snippets[id] = &updated
+
+	revision := updated
+	revision.UserID = userID
+	revision.Created = now
+	model.db.revisions[id] = append(model.db.revisions[id], &revision)
+
+	return nil
+}
+
+func (model *MemorySnippetModel) Extend(id int, expires time.Time) error {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	snippet, ok := model.db.live(id)
+	if !ok {
+		return ErrNoRecord
+	}
+	extended := *snippet
+	extended.Expires = utcOrZero(expires)
+	model.db.snippets[id] = &extended
+
+	return nil
+}
+
+func (model *MemorySnippetModel) Delete(id int) error {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	if _, ok := model.db.snippets[id]; !ok {
+		return ErrNoRecord
+	}
+	model.db.remove(id)
+
+	return nil
+}
+
+func (model *MemorySnippetModel) Get(id int) (*Snippet, error) {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	snippet, ok := model.db.live(id)
+	if !ok {
+		return nil, ErrNoRecord
+	}
+	return model.db.withAuthor(snippet), nil
+}
+
+func (model *MemorySnippetModel) GetBySlug(slug string) (*Snippet, error) {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	return model.db.getBySlug(slug)
+}
+
+// getBySlug returns a copy of the live snippet with the given slug. The
+// caller must hold db.mu.
+func (db *memoryDB) getBySlug(slug string) (*Snippet, error) {
+	if _, ok := db.burned[slug]; ok {
+		return nil, ErrBurned
+	}
+
+	snippet := db.bySlug(slug)
+	if snippet == nil {
+		return nil, ErrNoRecord
+	}
+	snippet, ok := db.live(snippet.ID)
+	if !ok {
+		return nil, ErrNoRecord
+	}
+	return db.withAuthor(snippet), nil
+}
+
+func (model *MemorySnippetModel) Burn(slug string) (*Snippet, error) {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	snippet, err := model.db.getBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	model.db.remove(snippet.ID)
+	model.db.burned[slug] = time.Now().UTC()
+
+	return snippet, nil
+}
+
+func (model *MemorySnippetModel) DeleteExpired(limit int, archive bool) (int, error) {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	now := time.Now().UTC()
+	stale := make([]*Snippet, 0, 10)
+	for _, snippet := range model.db.snippets {
+		if expired(snippet, now) {
+			stale = append(stale, snippet)
+		}
+	}
+	slices.SortFunc(stale, func(a, b *Snippet) int {
+		if c := a.Expires.Compare(b.Expires); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	stale = stale[:min(limit, len(stale))]
+
+	for _, snippet := range stale {
+		if archive {
+			model.db.archived[snippet.ID] = snippet
+		}
+		model.db.remove(snippet.ID)
+	}
+
+	return len(stale), nil
+}
+
+func (model *MemorySnippetModel) Latest() ([]*Snippet, error) {
+	return model.Page(10, 0)
+}
+
+func (model *MemorySnippetModel) Page(limit, offset int) ([]*Snippet, error) {
+	snippets := model.filter(func(s *Snippet) bool {
+		return s.Visibility == VisibilityPublic && !s.BurnAfterReading
+	})
+	if offset >= len(snippets) {
+		return []*Snippet{}, nil
+	}
+	return snippets[offset:min(offset+limit, len(snippets))], nil
+}
+
+func (model *MemorySnippetModel) ByUser(userID int) ([]*Snippet, error) {
+	return model.filter(func(s *Snippet) bool { return s.UserID == userID }), nil
+}
+
+func (model *MemorySnippetModel) After(id, limit int) ([]*Snippet, error) {
+	snippets := model.filter(func(s *Snippet) bool { return s.ID > id })
+	slices.Reverse(snippets)
+	return snippets[:min(limit, len(snippets))], nil
+}
+
+// filter returns the non-expired snippets matching keep, newest first.
+func (model *MemorySnippetModel) filter(keep func(*Snippet) bool) []*Snippet {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	snippets := make([]*Snippet, 0, 10)
+	for id := range model.db.snippets {
+		snippet, ok := model.db.live(id)
+		if ok && keep(snippet) {
+			snippets = append(snippets, model.db.withAuthor(snippet))
+		}
+	}
+	slices.SortFunc(snippets, func(a, b *Snippet) int { return b.ID - a.ID })
+
+	return snippets
+}
+
+func (model *MemorySnippetModel) History(id int) ([]*Snippet, error) {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	snippet, ok := model.db.live(id)
+	if !ok {
+		return []*Snippet{}, nil
+	}
+
+	revisions := model.db.revisions[id]
+	history := make([]*Snippet, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := model.db.withAuthor(revisions[i])
+		revision.Expires = snippet.Expires
+		history = append(history, revision)
+	}
+	return history, nil
+}
+
+func (model *MemorySnippetModel) Revision(id, revision int) (*Snippet, error) {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	snippet, ok := model.db.live(id)
+	if !ok {
+		return nil, ErrNoRecord
+	}
+
+	revisions := model.db.revisions[id]
+	if revision < 1 || revision > len(revisions) {
+		return nil, ErrNoRecord
+	}
+
+	s := model.db.withAuthor(revisions[revision-1])
+	s.Expires = snippet.Expires
+	return s, nil
+}
+
+func (model *MemoryUserModel) Insert(name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	for _, user := range model.db.users {
+		if user.Email == email {
+			return ErrDuplicateEmail
+		}
+	}
+
+	model.db.lastUserID++
+	model.db.users[model.db.lastUserID] = &User{
+		ID:             model.db.lastUserID,
+		Name:           name,
+		Email:          email,
+		HashedPassword: hashedPassword,
+		Created:        time.Now().UTC(),
+	}
+
+	return nil
+}
+
+func (model *MemoryUserModel) Authenticate(email, password string) (int, error) {
+	model.db.mu.RLock()
+	var found *User
+	for _, user := range model.db.users {
+		if user.Email == email {
+			found = user
+			break
+		}
+	}
+	model.db.mu.RUnlock()
+
+	if found == nil {
+		return 0, ErrInvaildCredentials
+	}
+
+	err := bcrypt.CompareHashAndPassword(found.HashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, ErrInvaildCredentials
+		}
+		return 0, err
+	}
+
+	return found.ID, nil
+}
+
+func (model *MemoryUserModel) Exists(id int) (bool, error) {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	_, ok := model.db.users[id]
+	return ok, nil
+}
+
+func (model *MemoryUserModel) Get(id int) (*User, error) {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	user, ok := model.db.users[id]
+	if !ok {
+		return nil, ErrNoRecord
+	}
+
+	u := *user
+	u.HashedPassword = nil
+	return &u, nil
+}
+
+func (model *MemoryTokenModel) Insert(userID int, name string, expires time.Time) (string, error) {
+	plaintext, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	model.db.lastTokenID++
+	model.db.tokens[model.db.lastTokenID] = &tokenRecord{
+		Token: Token{
+			ID:      model.db.lastTokenID,
+			UserID:  userID,
+			Name:    name,
+			Created: time.Now().UTC(),
+			Expires: expires,
+		},
+		hash: hash,
+	}
+
+	return plaintext, nil
+}
+
+func (model *MemoryTokenModel) ByUser(userID int) ([]*Token, error) {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	tokens := []*Token{}
+	for _, record := range model.db.tokens {
+		if record.UserID == userID {
+			token := record.Token
+			tokens = append(tokens, &token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b *Token) int { return b.ID - a.ID })
+
+	return tokens, nil
+}
+
+func (model *MemoryTokenModel) Delete(id, userID int) error {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	record, ok := model.db.tokens[id]
+	if !ok || record.UserID != userID {
+		return ErrNoRecord
+	}
+	delete(model.db.tokens, id)
+
+	return nil
+}
+
+func (model *MemoryTokenModel) Authenticate(plaintext string) (int, error) {
+	hash := hashToken(plaintext)
+
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, record := range model.db.tokens {
+		if record.hash != hash {
+			continue
+		}
+		if !record.Expires.IsZero() && !record.Expires.After(now) {
+			return 0, ErrInvaildCredentials
+		}
+		record.LastUsed = now
+		return record.UserID, nil
+	}
+
+	return 0, ErrInvaildCredentials
+}
+
+func (model *MemoryNotificationModel) QueueExpiryReminders(lead time.Duration) (int, error) {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	// snippet ids and the expiries their owners have been notified of
+	notified := make(map[int]map[int64]bool)
+	for _, notification := range model.db.notifications {
+		if notified[notification.SnippetID] == nil {
+			notified[notification.SnippetID] = make(map[int64]bool)
+		}
+		notified[notification.SnippetID][notification.Expires.UnixNano()] = true
+	}
+
+	now := time.Now().UTC()
+	n := 0
+	for _, snippet := range model.db.snippets {
+		if snippet.Expires.IsZero() || expired(snippet, now) || snippet.Expires.After(now.Add(lead)) {
+			continue
+		}
+		if notified[snippet.ID][snippet.Expires.UnixNano()] {
+			continue
+		}
+
+		model.db.lastNotificationID++
+		model.db.notifications[model.db.lastNotificationID] = &Notification{
+			ID:        model.db.lastNotificationID,
+			UserID:    snippet.UserID,
+			SnippetID: snippet.ID,
+			Slug:      snippet.Slug,
+			Expires:   snippet.Expires,
+			Created:   now,
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func (model *MemoryNotificationModel) ByUser(userID int) ([]*Notification, error) {
+	notifications := model.pending(func(n *Notification) bool { return n.UserID == userID })
+	slices.SortFunc(notifications, func(a, b *Notification) int {
+		if c := a.Expires.Compare(b.Expires); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	return notifications, nil
+}
+
+func (model *MemoryNotificationModel) Unseen(userID int) (int, error) {
+	notifications := model.pending(func(n *Notification) bool { return n.UserID == userID && !n.Seen })
+	return len(notifications), nil
+}
+
+func (model *MemoryNotificationModel) MarkSeen(userID int) error {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	for _, notification := range model.db.notifications {
+		if notification.UserID == userID {
+			notification.Seen = true
+		}
+	}
+	return nil
+}
+
+func (model *MemoryNotificationModel) Unemailed(limit int) ([]*Notification, error) {
+	notifications := model.pending(func(n *Notification) bool { return !n.Emailed })
+	slices.SortFunc(notifications, func(a, b *Notification) int { return a.ID - b.ID })
+	return notifications[:min(limit, len(notifications))], nil
+}
+
+func (model *MemoryNotificationModel) MarkEmailed(id int) error {
+	model.db.mu.Lock()
+	defer model.db.mu.Unlock()
+
+	notification, ok := model.db.notifications[id]
+	if !ok {
+		return ErrNoRecord
+	}
+	notification.Emailed = true
+	return nil
+}
+
+// pending returns copies of the notifications matching keep that still
+// apply to their snippet, in no particular order.
+func (model *MemoryNotificationModel) pending(keep func(*Notification) bool) []*Notification {
+	model.db.mu.RLock()
+	defer model.db.mu.RUnlock()
+
+	notifications := []*Notification{}
+	for _, notification := range model.db.notifications {
+		snippet, ok := model.db.live(notification.SnippetID)
+		if ok && snippet.Expires.Equal(notification.Expires) && keep(notification) {
+			n := *notification
+			notifications = append(notifications, &n)
+		}
+	}
+	return notifications
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Notification warns a user that one of their snippets is about to expire.
+// Expires is the expiry it warns about: once the snippet is extended, or has
+// expired, the notification no longer applies and stores stop returning it.
+// Seen records whether the user has looked at it in the app and Emailed
+// whether it has been sent by email.
+type Notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"userId"`
+	SnippetID int       `json:"snippetId"`
+	Slug      string    `json:"slug"`
+	Expires   time.Time `json:"expires"`
+	Created   time.Time `json:"created"`
+	Seen      bool      `json:"seen"`
+	Emailed   bool      `json:"emailed"`
+}
+
+type NotificationModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// notificationColumns selects a notifications row joined as n with its
+// snippet as s, in the order scanRowIntoNotification expects them.
+const notificationColumns = `n.id, n.user_id, n.snippet_id, s.slug, n.expires, n.created, n.seen, n.emailed`
+
+// pendingNotifications narrows notifications down to those that still
+// apply: the snippet hasn't been extended since and hasn't expired yet. It
+// takes the current time as its only argument.
+const pendingNotifications = `
+	FROM notifications n
+	INNER JOIN snippets s ON s.id = n.snippet_id AND s.expires = n.expires
+	WHERE s.expires > ?
+`
+
+// QueueExpiryReminders notifies the owner of every snippet that expires
+// within lead from now, unless they have already been notified of that
+// expiry, and returns how many notifications it queued.
+func (model *NotificationModel) QueueExpiryReminders(lead time.Duration) (int, error) {
+	now := time.Now().UTC()
+	queryStatement := `
+		INSERT INTO notifications (user_id, snippet_id, expires, created)
+		SELECT s.user_id, s.id, s.expires, ? FROM snippets s
+		WHERE s.expires > ? AND s.expires <= ? AND NOT EXISTS (
+			SELECT 1 FROM notifications n WHERE n.snippet_id = s.id AND n.expires = s.expires
+		)
+	`
+	result, err := model.DB.Exec(model.Dialect.rebind(queryStatement), now, now, now.Add(lead))
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// ByUser returns the user's pending notifications, the soonest expiry first.
+func (model *NotificationModel) ByUser(userID int) ([]*Notification, error) {
+	queryStatement := `
+		SELECT ` + notificationColumns + pendingNotifications + ` AND n.user_id = ?
+		ORDER BY n.expires, n.id
+	`
+	return model.query(queryStatement, time.Now().UTC(), userID)
+}
+
+// Unseen returns how many pending notifications the user hasn't seen.
+func (model *NotificationModel) Unseen(userID int) (int, error) {
+	queryStatement := `SELECT COUNT(*)` + pendingNotifications + ` AND n.user_id = ? AND n.seen = ?`
+
+	var n int
+	err := model.DB.QueryRow(model.Dialect.rebind(queryStatement), time.Now().UTC(), userID, false).Scan(&n)
+	return n, err
+}
+
+// MarkSeen records that the user has seen all of their notifications.
+func (model *NotificationModel) MarkSeen(userID int) error {
+	queryStatement := `UPDATE notifications SET seen = ? WHERE user_id = ? AND seen = ?`
+	_, err := model.DB.Exec(model.Dialect.rebind(queryStatement), true, userID, false)
+	return err
+}
+
+// Unemailed returns up to limit pending notifications that haven't been
+// emailed yet, oldest first.
+func (model *NotificationModel) Unemailed(limit int) ([]*Notification, error) {
+	queryStatement := `
+		SELECT ` + notificationColumns + pendingNotifications + ` AND n.emailed = ?
+		ORDER BY n.id
+		LIMIT ?
+	`
+	return model.query(queryStatement, time.Now().UTC(), false, limit)
+}
+
+// MarkEmailed records that a notification has been emailed.
+func (model *NotificationModel) MarkEmailed(id int) error {
+	queryStatement := `UPDATE notifications SET emailed = ? WHERE id = ?`
+	result, err := model.DB.Exec(model.Dialect.rebind(queryStatement), true, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+func (model *NotificationModel) query(queryStatement string, args ...any) ([]*Notification, error) {
+	rows, err := model.DB.Query(model.Dialect.rebind(queryStatement), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		notification, err := scanRowIntoNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+func scanRowIntoNotification(row scanner) (*Notification, error) {
+	notification := new(Notification)
+	err := row.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.SnippetID,
+		&notification.Slug,
+		&notification.Expires,
+		&notification.Created,
+		&notification.Seen,
+		&notification.Emailed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
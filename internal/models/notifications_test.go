@@ -0,0 +1,106 @@
+package models_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+func TestQueueExpiryRemindersOncePerExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		// stores returns the stores to test and the id of a user in them
+		stores func(t *testing.T) (models.SnippetStore, models.NotificationStore, int)
+	}{
+		{"SQLite", func(t *testing.T) (models.SnippetStore, models.NotificationStore, int) {
+			db := newTestDB(t)
+			return &models.SnippetModel{DB: db, Dialect: models.SQLite},
+				&models.NotificationModel{DB: db, Dialect: models.SQLite},
+				insertUser(t, db, "alice@example.com")
+		}},
+		{"Memory", func(t *testing.T) (models.SnippetStore, models.NotificationStore, int) {
+			memory := models.NewMemoryModels()
+			if err := memory.Users.Insert("Alice", "alice@example.com", "password123"); err != nil {
+				t.Fatal(err)
+			}
+			userID, err := memory.Users.Authenticate("alice@example.com", "password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return memory.Snippets, memory.Notifications, userID
+		}},
+	}
+
+	const lead = 72 * time.Hour
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippets, notifications, userID := tt.stores(t)
+
+			insert := func(title string, expires time.Time) int {
+				t.Helper()
+				slug, err := snippets.Insert(&models.Snippet{
+					UserID:     userID,
+					Title:      title,
+					Content:    title,
+					Visibility: models.VisibilityPublic,
+				}, expires)
+				if err != nil {
+					t.Fatal(err)
+				}
+				snippet, err := snippets.GetBySlug(slug)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return snippet.ID
+			}
+			soon := insert("Soon", time.Now().Add(24*time.Hour))
+			sooner := insert("Sooner", time.Now().Add(time.Hour))
+			insert("Later", time.Now().Add(10*24*time.Hour))
+			insert("Never", time.Time{})
+
+			queue := func(want int) {
+				t.Helper()
+				n, err := notifications.QueueExpiryReminders(lead)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != want {
+					t.Errorf("queued %d reminders; want %d", n, want)
+				}
+			}
+			reminded := func(want ...int) {
+				t.Helper()
+				pending, err := notifications.ByUser(userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ids []int
+				for _, notification := range pending {
+					ids = append(ids, notification.SnippetID)
+				}
+				if !slices.Equal(ids, want) {
+					t.Errorf("reminded of snippets %v; want %v", ids, want)
+				}
+			}
+
+			queue(2)
+			reminded(sooner, soon)
+
+			// queueing again adds nothing
+			queue(0)
+			reminded(sooner, soon)
+
+			// a new expiry, still within the lead time, is reminded of once
+			// more, and the reminder of the old one no longer applies
+			if err := snippets.Extend(sooner, time.Now().Add(48*time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			queue(1)
+			queue(0)
+			reminded(soon, sooner)
+		})
+	}
+}
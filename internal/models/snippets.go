@@ -1,66 +1,674 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/keyring"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Visibility controls who can find and read a snippet.
+type Visibility string
+
+const (
+	// VisibilityPublic snippets are listed on the home page and readable by
+	// anyone.
+	VisibilityPublic Visibility = "public"
+	// VisibilityUnlisted snippets are readable by anyone with the link but
+	// aren't listed.
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPrivate snippets are only readable by their owner.
+	VisibilityPrivate Visibility = "private"
 )
 
+// Snippet is either the current state of a snippet or, when returned from
+// History or Revision, one immutable version of it. For a version, UserID and
+// Author name whoever made that change and Created is when they made it.
+//
+// Encrypted snippets were encrypted in the browser with a key the server
+// never sees; their Content is the ciphertext. BurnAfterReading snippets are
+// deleted as soon as someone other than their author reads them. PassphraseHash is the bcrypt hash of the passphrase
+// needed to read the snippet, or nil if it doesn't need one. Expires is
+// the zero time for a snippet that never expires.
 type Snippet struct {
-	ID      int       `json:"id"`
-	Title   string    `json:"title"`
-	Content string    `json:"content"`
-	Created time.Time `json:"created"`
-	Expires time.Time `json:"expires"`
+	ID               int        `json:"id"`
+	Slug             string     `json:"slug"`
+	Revision         int        `json:"revision"`
+	UserID           int        `json:"userId"`
+	Author           string     `json:"author"`
+	Title            string     `json:"title"`
+	Content          string     `json:"content"`
+	Language         string     `json:"language"`
+	Visibility       Visibility `json:"visibility"`
+	Encrypted        bool       `json:"encrypted"`
+	BurnAfterReading bool       `json:"burnAfterReading"`
+	PassphraseHash   []byte     `json:"-"`
+	Created          time.Time  `json:"created"`
+	Expires          time.Time  `json:"expires"`
+}
+
+// SetPassphrase protects the snippet with a passphrase, or removes the
+// protection if passphrase is empty. Only a bcrypt hash is kept.
+func (s *Snippet) SetPassphrase(passphrase string) error {
+	if passphrase == "" {
+		s.PassphraseHash = nil
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(passphrase), 12)
+	if err != nil {
+		return err
+	}
+	s.PassphraseHash = hash
+	return nil
+}
+
+// Protected reports whether the snippet needs a passphrase to be read.
+func (s *Snippet) Protected() bool {
+	return len(s.PassphraseHash) > 0
+}
+
+// MatchesPassphrase reports whether passphrase unlocks the snippet.
+func (s *Snippet) MatchesPassphrase(passphrase string) bool {
+	return bcrypt.CompareHashAndPassword(s.PassphraseHash, []byte(passphrase)) == nil
+}
+
+// VisibleTo reports whether the user with the given id, or 0 for an
+// anonymous visitor, may read the snippet.
+func (s *Snippet) VisibleTo(userID int) bool {
+	return s.Visibility != VisibilityPrivate || s.UserID == userID
 }
 
+// slugAttempts is how many random slugs Insert tries before giving up. With
+// 72 random bits a collision is already unlikely, so a second attempt is
+// practically never needed.
+const slugAttempts = 3
+
+// newSlug returns a random, URL-safe identifier for a snippet. Unlike the
+// sequential ids, slugs can't be guessed by counting.
+func newSlug() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SnippetModel stores snippets in a SQL database. With Keys set, the content
+// of every snippet and revision, and the title too if EncryptTitles is set,
+// is encrypted before it is written and the ID of the key used is stored in
+// the same row. Rows written without keys, or before they were configured,
+// are still read as plaintext; Rekey brings them up to date.
 type SnippetModel struct {
-	DB *sql.DB
+	DB            *sql.DB
+	Dialect       Dialect
+	Keys          *keyring.Keyring
+	EncryptTitles bool
+}
+
+// snippetColumns is the column list every snippet query selects, in the order
+// scanRowIntoSnippet expects them. The author's name comes from the users
+// table, so queries using it must join users as u and snippets as s.
+const snippetColumns = `s.id, s.slug, s.revision, s.user_id, u.name, s.title, s.content, s.language, s.visibility,
+	s.encrypted, s.burn_after_reading, s.passphrase_hash, s.created, s.expires, s.title_key_id, s.key_id`
+
+// revisionColumns selects a snippet_revisions row joined as r with its
+// snippet as s and the user who made the change as u, in the same order as
+// snippetColumns. Only the title and content are versioned, so every
+// revision has the snippet's current language, visibility and so on.
+const revisionColumns = `r.snippet_id, s.slug, r.revision, r.user_id, u.name, r.title, r.content, s.language, s.visibility,
+	s.encrypted, s.burn_after_reading, s.passphrase_hash, r.created, s.expires, r.title_key_id, r.key_id`
+
+// recordRevision stores the title and content a snippet has just been given
+// as its current revision, attributed to a user at a given time.
+const recordRevision = `
+	INSERT INTO snippet_revisions (snippet_id, revision, user_id, title_key_id, title, key_id, content, created)
+	VALUES (?, (SELECT revision FROM snippets WHERE id = ?), ?, ?, ?, ?, ?, ?)
+`
+
+// sealedFields are a snippet's title and content as they are stored, each
+// with the ID of the key it is encrypted with or "" if it isn't.
+type sealedFields struct {
+	titleKeyID, title string
+	keyID, content    string
+}
+
+// seal encrypts title and content for storage as the model is configured to.
+func (model *SnippetModel) seal(title, content string) (sealedFields, error) {
+	var f sealedFields
+	var err error
+
+	f.titleKeyID, f.title = "", title
+	if model.EncryptTitles {
+		f.titleKeyID, f.title, err = model.Keys.Seal(title, "title")
+		if err != nil {
+			return f, err
+		}
+	}
+
+	f.keyID, f.content, err = model.Keys.Seal(content, "content")
+	return f, err
+}
+
+// open decrypts fields read from the database.
+func (model *SnippetModel) open(f sealedFields) (title, content string, err error) {
+	title, err = model.Keys.Open(f.titleKeyID, f.title, "title")
+	if err != nil {
+		return "", "", err
+	}
+	content, err = model.Keys.Open(f.keyID, f.content, "content")
+	if err != nil {
+		return "", "", err
+	}
+	return title, content, nil
+}
+
+// neverExpires is stored in place of the zero expiry of snippets that never
+// expire, so that the queries comparing expiries need no special case. It is
+// the latest date a MySQL DATETIME can hold.
+var neverExpires = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// storedExpiry returns expires as it is written to the database.
+func storedExpiry(expires time.Time) time.Time {
+	if expires.IsZero() {
+		return neverExpires
+	}
+	return expires.UTC()
+}
+
+// Insert stores a new snippet by snippet.UserID with its Title, Content,
+// Language, Visibility, Encrypted, BurnAfterReading and PassphraseHash,
+// expiring at expires, or never if expires is the zero time, and returns the
+// random slug it was given. The other fields of snippet are ignored.
+func (model *SnippetModel) Insert(snippet *Snippet, expires time.Time) (string, error) {
+	for attempt := 1; ; attempt++ {
+		slug, err := newSlug()
+		if err != nil {
+			return "", err
+		}
+
+		err = model.insert(snippet, slug, expires)
+		if err == nil {
+			return slug, nil
+		}
+		if attempt == slugAttempts || !model.Dialect.isUniqueViolation(err, "snippets_uc_slug", "snippets.slug") {
+			return "", err
+		}
+	}
+}
+
+// insert stores a new snippet under the given slug. It runs in its own
+// transaction so that a slug collision, which aborts the transaction on
+// Postgres, can be retried.
+func (model *SnippetModel) insert(snippet *Snippet, slug string, expires time.Time) error {
+	tx, err := model.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	f, err := model.seal(snippet.Title, snippet.Content)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	queryStatement := `
+		INSERT INTO snippets (slug, user_id, revision, title_key_id, title, key_id, content, language, visibility,
+			encrypted, burn_after_reading, passphrase_hash, created, expires)
+		VALUES (?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	id, err := model.Dialect.insert(tx, queryStatement, slug, snippet.UserID, f.titleKeyID, f.title, f.keyID, f.content,
+		snippet.Language, snippet.Visibility, snippet.Encrypted, snippet.BurnAfterReading, nullBytes(snippet.PassphraseHash),
+		now, storedExpiry(expires))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(model.Dialect.rebind(recordRevision), id, id, snippet.UserID, f.titleKeyID, f.title, f.keyID,
+		f.content, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (model *SnippetModel) Insert(title, content string, expires int) (int, error) {
+// Update records a new revision of snippet.ID made by userID, with the
+// Title, Content, Language, Visibility, Encrypted, BurnAfterReading and
+// PassphraseHash of snippet, and sets it to expire at expires, or never if
+// expires is the zero time. Earlier revisions are kept and can be read back
+// with History and Revision.
+func (model *SnippetModel) Update(snippet *Snippet, userID int, expires time.Time) error {
+	tx, err := model.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	f, err := model.seal(snippet.Title, snippet.Content)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
 	queryStatement := `
-		INSERT INTO snippets (title, content, created, expires)
-		VALUES (?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))
+		UPDATE snippets
+		SET revision = revision + 1, title_key_id = ?, title = ?, key_id = ?, content = ?, language = ?,
+			visibility = ?, encrypted = ?, burn_after_reading = ?, passphrase_hash = ?, expires = ?
+		WHERE id = ?
 	`
-	result, err := model.DB.Exec(queryStatement, title, content, expires)
+	result, err := tx.Exec(model.Dialect.rebind(queryStatement), f.titleKeyID, f.title, f.keyID, f.content, snippet.Language,
+		snippet.Visibility, snippet.Encrypted, snippet.BurnAfterReading, nullBytes(snippet.PassphraseHash), storedExpiry(expires),
+		snippet.ID)
 	if err != nil {
-		return -1, nil
+		return err
+	}
+	if err = checkRowsAffected(result); err != nil {
+		return err
 	}
 
-	id, err := result.LastInsertId()
+	_, err = tx.Exec(model.Dialect.rebind(recordRevision), snippet.ID, snippet.ID, userID, f.titleKeyID, f.title,
+		f.keyID, f.content, now)
 	if err != nil {
-		return -1, nil
+		return err
 	}
-	return int(id), nil
+
+	return tx.Commit()
+}
+
+// Extend sets the snippet with the given id to expire at expires, or never
+// if expires is the zero time, without recording a revision. A snippet that
+// has already expired can't be brought back.
+func (model *SnippetModel) Extend(id int, expires time.Time) error {
+	queryStatement := `UPDATE snippets SET expires = ? WHERE id = ? AND expires > ?`
+	result, err := model.DB.Exec(model.Dialect.rebind(queryStatement), storedExpiry(expires), id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+// History returns every revision of a snippet, newest first.
+func (model *SnippetModel) History(id int) ([]*Snippet, error) {
+	queryStatement := `
+		SELECT ` + revisionColumns + ` FROM snippet_revisions r
+		INNER JOIN snippets s ON s.id = r.snippet_id
+		INNER JOIN users u ON u.id = r.user_id
+		WHERE s.expires > ? AND r.snippet_id = ?
+		ORDER BY r.revision DESC
+	`
+	return model.query(queryStatement, time.Now().UTC(), id)
+}
+
+// Revision returns a single revision of a snippet.
+func (model *SnippetModel) Revision(id, revision int) (*Snippet, error) {
+	queryStatement := `
+		SELECT ` + revisionColumns + ` FROM snippet_revisions r
+		INNER JOIN snippets s ON s.id = r.snippet_id
+		INNER JOIN users u ON u.id = r.user_id
+		WHERE s.expires > ? AND r.snippet_id = ? AND r.revision = ?
+	`
+
+	row := model.DB.QueryRow(model.Dialect.rebind(queryStatement), time.Now().UTC(), id, revision)
+
+	snippet, err := model.scanRowIntoSnippet(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// Delete removes a snippet along with all of its revisions and
+// notifications.
+func (model *SnippetModel) Delete(id int) error {
+	tx, err := model.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(model.Dialect.rebind(`DELETE FROM snippet_revisions WHERE snippet_id = ?`), id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(model.Dialect.rebind(`DELETE FROM notifications WHERE snippet_id = ?`), id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(model.Dialect.rebind(`DELETE FROM snippets WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if err = checkRowsAffected(result); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (model *SnippetModel) Get(id int) (*Snippet, error) {
 	queryStatement := `
-		SELECT * FROM snippets
-		WHERE expires > UTC_TIMESTAMP() AND ID = ?
+		SELECT ` + snippetColumns + ` FROM snippets s
+		INNER JOIN users u ON u.id = s.user_id
+		WHERE s.expires > ? AND s.id = ?
 	`
 
-	row := model.DB.QueryRow(queryStatement, id)
+	row := model.DB.QueryRow(model.Dialect.rebind(queryStatement), time.Now().UTC(), id)
+
+	snippet, err := model.scanRowIntoSnippet(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// GetBySlug returns the non-expired snippet with the given slug, or
+// ErrBurned if it has been burned.
+func (model *SnippetModel) GetBySlug(slug string) (*Snippet, error) {
+	return model.getBySlug(model.DB, slug)
+}
+
+// getBySlug runs GetBySlug on db, which is either the database or a
+// transaction.
+func (model *SnippetModel) getBySlug(db execer, slug string) (*Snippet, error) {
+	queryStatement := `
+		SELECT ` + snippetColumns + ` FROM snippets s
+		INNER JOIN users u ON u.id = s.user_id
+		WHERE s.expires > ? AND s.slug = ?
+	`
+
+	row := db.QueryRow(model.Dialect.rebind(queryStatement), time.Now().UTC(), slug)
+
+	snippet, err := model.scanRowIntoSnippet(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.missing(db, slug)
+		}
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// missing explains why there is no snippet with the given slug: ErrBurned if
+// it has been burned and ErrNoRecord otherwise.
+func (model *SnippetModel) missing(db execer, slug string) error {
+	var n int
+	row := db.QueryRow(model.Dialect.rebind(`SELECT COUNT(*) FROM burned_snippets WHERE slug = ?`), slug)
+	if err := row.Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrBurned
+	}
+	return ErrNoRecord
+}
+
+// Burn reads the snippet with the given slug and deletes it, along with its
+// revisions and notifications, in the same transaction. The slug is
+// remembered so that later lookups return ErrBurned rather than ErrNoRecord.
+// Only one caller can burn a snippet; everyone after it gets ErrBurned.
+func (model *SnippetModel) Burn(slug string) (*Snippet, error) {
+	tx, err := model.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	snippet, err := scanRowIntoSnippet(row)
+	snippet, err := model.getBySlug(tx, slug)
 	if err != nil {
-		// if errors.Is(err, ErrNoRecord) {
-		// 	return nil, ErrNoRecord
-		// }
-		return nil, ErrNoRecord
+		return nil, err
+	}
+
+	_, err = tx.Exec(model.Dialect.rebind(`DELETE FROM snippet_revisions WHERE snippet_id = ?`), snippet.ID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(model.Dialect.rebind(`DELETE FROM notifications WHERE snippet_id = ?`), snippet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a concurrent reader that got here first has already deleted the row,
+	// so the snippet is theirs
+	result, err := tx.Exec(model.Dialect.rebind(`DELETE FROM snippets WHERE id = ?`), snippet.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkRowsAffected(result); err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			return nil, ErrBurned
+		}
+		return nil, err
+	}
+
+	queryStatement := `INSERT INTO burned_snippets (slug, burned) VALUES (?, ?)`
+	_, err = tx.Exec(model.Dialect.rebind(queryStatement), slug, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
 	}
 	return snippet, nil
 }
 
+// archivedColumns are the columns archived_snippets copies from snippets.
+const archivedColumns = `id, slug, user_id, revision, title_key_id, title, key_id, content, language, visibility,
+	encrypted, burn_after_reading, passphrase_hash, created, expires`
+
+// DeleteExpired removes up to limit expired snippets, the longest expired
+// first, along with their revisions and notifications, and returns how many
+// it removed. With archive set, the current revision of each is copied to
+// archived_snippets first, encrypted just as it was stored.
+func (model *SnippetModel) DeleteExpired(limit int, archive bool) (int, error) {
+	tx, err := model.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	queryStatement := `SELECT id FROM snippets WHERE expires <= ? ORDER BY expires, id LIMIT ?`
+	rows, err := tx.Query(model.Dialect.rebind(queryStatement), now, limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []any
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	in := "(?" + strings.Repeat(", ?", len(ids)-1) + ")"
+
+	if archive {
+		queryStatement = `
+			INSERT INTO archived_snippets (` + archivedColumns + `, archived)
+			SELECT ` + archivedColumns + `, ? FROM snippets WHERE id IN ` + in
+		_, err = tx.Exec(model.Dialect.rebind(queryStatement), append([]any{now}, ids...)...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(model.Dialect.rebind(`DELETE FROM snippet_revisions WHERE snippet_id IN `+in), ids...)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(model.Dialect.rebind(`DELETE FROM notifications WHERE snippet_id IN `+in), ids...)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(model.Dialect.rebind(`DELETE FROM snippets WHERE id IN `+in), ids...)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
 func (model *SnippetModel) Latest() ([]*Snippet, error) {
-	snippets := make([]*Snippet, 0, 10)
+	return model.Page(10, 0)
+}
+
+// Page returns up to limit non-expired public snippets, newest first,
+// skipping the first offset of them. Burn after reading snippets are left
+// out, as anyone following the listing would burn them.
+func (model *SnippetModel) Page(limit, offset int) ([]*Snippet, error) {
+	queryStatement := `
+		SELECT ` + snippetColumns + ` FROM snippets s
+		INNER JOIN users u ON u.id = s.user_id
+		WHERE s.expires > ? AND s.visibility = ? AND s.burn_after_reading = ?
+		ORDER BY s.id DESC
+		LIMIT ? OFFSET ?
+	`
+	return model.query(queryStatement, time.Now().UTC(), VisibilityPublic, false, limit, offset)
+}
+
+// ByUser returns the non-expired snippets created by the given user, newest
+// first, whatever their visibility.
+func (model *SnippetModel) ByUser(userID int) ([]*Snippet, error) {
 	queryStatement := `
-		SELECT * FROM snippets
-		WHERE expires > UTC_TIMESTAMP()
-		ORDER BY id DESC
-		LIMIT 10
+		SELECT ` + snippetColumns + ` FROM snippets s
+		INNER JOIN users u ON u.id = s.user_id
+		WHERE s.expires > ? AND s.user_id = ?
+		ORDER BY s.id DESC
 	`
-	rows, err := model.DB.Query(queryStatement)
+	return model.query(queryStatement, time.Now().UTC(), userID)
+}
+
+// Rekey re-encrypts up to batchSize snippets, revisions and archived snippets
+// whose title or content isn't stored the way the model is currently
+// configured to store it: under the current key, or in plaintext if the model
+// has no keys. It returns how many rows it rewrote, so callers can repeat it
+// until that is 0. Each call is a transaction of its own, which keeps locks
+// short on large tables.
+func (model *SnippetModel) Rekey(batchSize int) (int, error) {
+	tx, err := model.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	total := 0
+	for _, table := range []string{"snippets", "snippet_revisions", "archived_snippets"} {
+		n, err := model.rekeyTable(tx, table, batchSize)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// rekeyTable re-encrypts up to batchSize out of date rows of table, which is
+// snippets, snippet_revisions or archived_snippets.
+func (model *SnippetModel) rekeyTable(tx *sql.Tx, table string, batchSize int) (int, error) {
+	titleKeyID := ""
+	if model.EncryptTitles {
+		titleKeyID = model.Keys.Current()
+	}
+
+	queryStatement := `
+		SELECT id, title_key_id, title, key_id, content FROM ` + table + `
+		WHERE title_key_id <> ? OR key_id <> ?
+		ORDER BY id
+		LIMIT ?
+	`
+	rows, err := tx.Query(model.Dialect.rebind(queryStatement), titleKeyID, model.Keys.Current(), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// the rows are read in full before any are updated, as a transaction's
+	// connection can't run a statement while a result set is still open
+	var ids []int
+	var stale []sealedFields
+	for rows.Next() {
+		var id int
+		var f sealedFields
+		if err := rows.Scan(&id, &f.titleKeyID, &f.title, &f.keyID, &f.content); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		stale = append(stale, f)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	queryStatement = `UPDATE ` + table + ` SET title_key_id = ?, title = ?, key_id = ?, content = ? WHERE id = ?`
+	for i, id := range ids {
+		title, content, err := model.open(stale[i])
+		if err != nil {
+			return 0, fmt.Errorf("%s %d: %w", table, id, err)
+		}
+		f, err := model.seal(title, content)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(model.Dialect.rebind(queryStatement), f.titleKeyID, f.title, f.keyID, f.content, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+// After returns up to limit non-expired snippets of any visibility whose ids
+// are greater than id, in id order. It is meant for walking through every
+// snippet in batches, for example to build a search index.
+func (model *SnippetModel) After(id, limit int) ([]*Snippet, error) {
+	queryStatement := `
+		SELECT ` + snippetColumns + ` FROM snippets s
+		INNER JOIN users u ON u.id = s.user_id
+		WHERE s.expires > ? AND s.id > ?
+		ORDER BY s.id
+		LIMIT ?
+	`
+	return model.query(queryStatement, time.Now().UTC(), id, limit)
+}
+
+func (model *SnippetModel) query(queryStatement string, args ...any) ([]*Snippet, error) {
+	snippets := make([]*Snippet, 0, 10)
+	rows, err := model.DB.Query(model.Dialect.rebind(queryStatement), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +676,7 @@ func (model *SnippetModel) Latest() ([]*Snippet, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		snippet, err := scanRowIntoSnippet(rows)
+		snippet, err := model.scanRowIntoSnippet(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -83,21 +691,61 @@ func (model *SnippetModel) Latest() ([]*Snippet, error) {
 	return snippets, nil
 }
 
+// nullBytes stores an empty hash as NULL.
+func nullBytes(b []byte) sql.NullString {
+	return sql.NullString{String: string(b), Valid: len(b) > 0}
+}
+
+// checkRowsAffected turns a statement that touched no rows into ErrNoRecord.
+func checkRowsAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
 type scanner interface {
 	Scan(dest ...any) error
 }
 
-func scanRowIntoSnippet(row scanner) (*Snippet, error) {
+func (model *SnippetModel) scanRowIntoSnippet(row scanner) (*Snippet, error) {
 	snippet := new(Snippet)
+	var passphraseHash sql.NullString
+	var f sealedFields
 	err := row.Scan(
 		&snippet.ID,
-		&snippet.Title,
-		&snippet.Content,
+		&snippet.Slug,
+		&snippet.Revision,
+		&snippet.UserID,
+		&snippet.Author,
+		&f.title,
+		&f.content,
+		&snippet.Language,
+		&snippet.Visibility,
+		&snippet.Encrypted,
+		&snippet.BurnAfterReading,
+		&passphraseHash,
 		&snippet.Created,
 		&snippet.Expires,
+		&f.titleKeyID,
+		&f.keyID,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if !snippet.Expires.Before(neverExpires) {
+		snippet.Expires = time.Time{}
+	}
+	snippet.Title, snippet.Content, err = model.open(f)
+	if err != nil {
+		return nil, fmt.Errorf("snippet %d: %w", snippet.ID, err)
+	}
+	if passphraseHash.Valid {
+		snippet.PassphraseHash = []byte(passphraseHash.String)
+	}
 	return snippet, nil
 }
@@ -0,0 +1,236 @@
+package models_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Yusufdot101/snippetbox/internal/keyring"
+	"github.com/Yusufdot101/snippetbox/internal/migrations"
+	"github.com/Yusufdot101/snippetbox/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+// newTestDB returns a migrated SQLite database in a temporary directory.
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "snippetbox.db")+"?_pragma=foreign_keys(1)&_time_format=sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := (&migrations.Migrator{DB: db, Dialect: models.SQLite}).Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// insertUser adds a user with the password "password123" and returns its id.
+func insertUser(t *testing.T, db *sql.DB, email string) int {
+	users := &models.UserModel{DB: db, Dialect: models.SQLite}
+	if err := users.Insert("Alice", email, "password123"); err != nil {
+		t.Fatal(err)
+	}
+	userID, err := users.Authenticate(email, "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userID
+}
+
+func TestUnreadableSnippetIsAnError(t *testing.T) {
+	db := newTestDB(t)
+	userID := insertUser(t, db, "alice@example.com")
+
+	keys, err := keyring.Parse("k1=AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypting := &models.SnippetModel{DB: db, Dialect: models.SQLite, Keys: keys}
+	slug, err := encrypting.Insert(&models.Snippet{
+		UserID:     userID,
+		Title:      "Secret",
+		Content:    "encrypted at rest",
+		Visibility: models.VisibilityPublic,
+	}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snippet, err := encrypting.GetBySlug(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without the key the snippet is there but can't be read
+	keyless := &models.SnippetModel{DB: db, Dialect: models.SQLite}
+
+	if _, err := keyless.Get(snippet.ID); err == nil || errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Get without the key: got %v; want a decryption error", err)
+	}
+	if _, err := keyless.GetBySlug(slug); err == nil || errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("GetBySlug without the key: got %v; want a decryption error", err)
+	}
+	if _, err := keyless.Get(snippet.ID + 1); !errors.Is(err, models.ErrNoRecord) {
+		t.Errorf("Get of a missing snippet: got %v; want ErrNoRecord", err)
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive bool
+	}{
+		{"Delete", false},
+		{"Archive", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			userID := insertUser(t, db, "alice@example.com")
+			snippets := &models.SnippetModel{DB: db, Dialect: models.SQLite}
+
+			insert := func(title string, expires time.Time) string {
+				t.Helper()
+				slug, err := snippets.Insert(&models.Snippet{
+					UserID:     userID,
+					Title:      title,
+					Content:    title,
+					Visibility: models.VisibilityPublic,
+				}, expires)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return slug
+			}
+			expired := insert("Expired", time.Now().Add(-time.Hour))
+			current := insert("Current", time.Now().Add(time.Hour))
+			never := insert("Never", time.Time{})
+
+			n, err := snippets.DeleteExpired(100, tt.archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("deleted %d snippets; want 1", n)
+			}
+
+			// GetBySlug hides expired snippets anyway, so look at the table
+			var left int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM snippets WHERE slug = ?`, expired).Scan(&left); err != nil {
+				t.Fatal(err)
+			}
+			if left != 0 {
+				t.Error("expired snippet is still stored")
+			}
+			if _, err := snippets.GetBySlug(current); err != nil {
+				t.Errorf("current snippet: got %v", err)
+			}
+			snippet, err := snippets.GetBySlug(never)
+			if err != nil {
+				t.Fatalf("never expiring snippet: got %v", err)
+			}
+			if !snippet.Expires.IsZero() {
+				t.Errorf("never expiring snippet expires %v; want the zero time", snippet.Expires)
+			}
+
+			var archived []string
+			rows, err := db.Query(`SELECT slug FROM archived_snippets`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var slug string
+				if err := rows.Scan(&slug); err != nil {
+					t.Fatal(err)
+				}
+				archived = append(archived, slug)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string(nil)
+			if tt.archive {
+				want = []string{expired}
+			}
+			if !slices.Equal(archived, want) {
+				t.Errorf("archived %v; want %v", archived, want)
+			}
+
+			// nothing else has expired since
+			if n, err := snippets.DeleteExpired(100, tt.archive); err != nil || n != 0 {
+				t.Errorf("deleting again: got %d, %v; want 0, nil", n, err)
+			}
+		})
+	}
+}
+
+func TestSnippetModelRoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	userID := insertUser(t, db, "alice@example.com")
+	snippets := &models.SnippetModel{DB: db, Dialect: models.SQLite}
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	slug, err := snippets.Insert(&models.Snippet{
+		UserID:     userID,
+		Title:      "First",
+		Content:    "first content",
+		Language:   "go",
+		Visibility: models.VisibilityUnlisted,
+	}, expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slug) != 12 {
+		t.Errorf("got slug %q; want 12 characters", slug)
+	}
+
+	snippet, err := snippets.GetBySlug(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snippet.Slug != slug || snippet.UserID != userID || snippet.Author != "Alice" || snippet.Title != "First" ||
+		snippet.Content != "first content" || snippet.Language != "go" || snippet.Visibility != models.VisibilityUnlisted ||
+		snippet.Revision != 1 || !snippet.Expires.Equal(expires) {
+		t.Errorf("read back %+v", snippet)
+	}
+
+	snippet.Title, snippet.Content = "Second", "second content"
+	if err := snippets.Update(snippet, userID, expires); err != nil {
+		t.Fatal(err)
+	}
+	history, err := snippets.History(snippet.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, revision := range history {
+		titles = append(titles, fmt.Sprintf("%d %s", revision.Revision, revision.Title))
+	}
+	if want := []string{"2 Second", "1 First"}; !slices.Equal(titles, want) {
+		t.Errorf("got history %v; want %v", titles, want)
+	}
+
+	burned, err := snippets.Burn(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burned.Title != "Second" || burned.Content != "second content" {
+		t.Errorf("burned %+v; want the latest revision", burned)
+	}
+	if _, err := snippets.GetBySlug(slug); !errors.Is(err, models.ErrBurned) {
+		t.Errorf("GetBySlug after burning: got %v; want ErrBurned", err)
+	}
+	if _, err := snippets.Burn(slug); !errors.Is(err, models.ErrBurned) {
+		t.Errorf("burning again: got %v; want ErrBurned", err)
+	}
+	if history, err := snippets.History(snippet.ID); err != nil || len(history) != 0 {
+		t.Errorf("history after burning: got %d revisions, %v; want none", len(history), err)
+	}
+}
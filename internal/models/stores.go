@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// SnippetStore is implemented by every backend that can hold snippets and
+// their revision history. Implementations must only return snippets that
+// haven't expired, and must return ErrNoRecord when a snippet doesn't exist,
+// or ErrBurned from GetBySlug and Burn when it has been burned.
+//
+// DeleteExpired removes up to limit expired snippets, and their revisions,
+// and returns how many it removed. With archive set they are kept aside in
+// the store rather than being lost.
+type SnippetStore interface {
+	Insert(snippet *Snippet, expires time.Time) (string, error)
+	Update(snippet *Snippet, userID int, expires time.Time) error
+	Extend(id int, expires time.Time) error
+	Delete(id int) error
+	Get(id int) (*Snippet, error)
+	GetBySlug(slug string) (*Snippet, error)
+	Burn(slug string) (*Snippet, error)
+	DeleteExpired(limit int, archive bool) (int, error)
+	Latest() ([]*Snippet, error)
+	Page(limit, offset int) ([]*Snippet, error)
+	ByUser(userID int) ([]*Snippet, error)
+	After(id, limit int) ([]*Snippet, error)
+	History(id int) ([]*Snippet, error)
+	Revision(id, revision int) (*Snippet, error)
+}
+
+// UserStore is implemented by every backend that can hold user accounts.
+// Insert must return ErrDuplicateEmail when the email is already taken and
+// Authenticate must return ErrInvaildCredentials on a bad email or password.
+type UserStore interface {
+	Insert(name, email, password string) error
+	Authenticate(email, password string) (int, error)
+	Exists(id int) (bool, error)
+	Get(id int) (*User, error)
+}
+
+// TokenStore is implemented by every backend that can hold personal access
+// tokens. Authenticate must return ErrInvaildCredentials for unknown or
+// expired tokens.
+type TokenStore interface {
+	Insert(userID int, name string, expires time.Time) (string, error)
+	ByUser(userID int) ([]*Token, error)
+	Delete(id, userID int) error
+	Authenticate(plaintext string) (int, error)
+}
+
+// NotificationStore is implemented by every backend that can hold
+// notifications. Only notifications that still apply to their snippet are
+// returned, as described on Notification.
+type NotificationStore interface {
+	QueueExpiryReminders(lead time.Duration) (int, error)
+	ByUser(userID int) ([]*Notification, error)
+	Unseen(userID int) (int, error)
+	MarkSeen(userID int) error
+	Unemailed(limit int) ([]*Notification, error)
+	MarkEmailed(id int) error
+}
+
+var (
+	_ SnippetStore = (*SnippetModel)(nil)
+	_ UserStore    = (*UserModel)(nil)
+	_ SnippetStore = (*MemorySnippetModel)(nil)
+	_ UserStore    = (*MemoryUserModel)(nil)
+	_ TokenStore   = (*TokenModel)(nil)
+	_ TokenStore   = (*MemoryTokenModel)(nil)
+
+	_ NotificationStore = (*NotificationModel)(nil)
+	_ NotificationStore = (*MemoryNotificationModel)(nil)
+)
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// tokenPrefix marks personal access tokens so they are easy to recognise,
+// e.g. by secret scanners.
+const tokenPrefix = "sbx_"
+
+// Token is a personal access token. Only a hash of the token is stored; the
+// plaintext is returned once by Insert and can't be recovered afterwards. A
+// zero LastUsed means the token has never been used and a zero Expires means
+// it never expires.
+type Token struct {
+	ID       int       `json:"id"`
+	UserID   int       `json:"userId"`
+	Name     string    `json:"name"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastUsed"`
+	Expires  time.Time `json:"expires"`
+}
+
+// newToken returns a random plaintext token along with the hash to store.
+func newToken() (plaintext, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	plaintext = tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return plaintext, hashToken(plaintext), nil
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a plaintext token. The
+// tokens are 256 random bits, so unlike passwords they don't need a slow,
+// salted hash.
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+type TokenModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+const tokenColumns = `id, user_id, name, created, last_used, expires`
+
+// Insert creates a token for userID and returns its plaintext. Pass a zero
+// expires for a token that never expires.
+func (model *TokenModel) Insert(userID int, name string, expires time.Time) (string, error) {
+	plaintext, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	queryStatement := `
+		INSERT INTO tokens (user_id, name, hash, created, expires)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err = model.DB.Exec(model.Dialect.rebind(queryStatement), userID, name, hash, time.Now().UTC(), nullTime(expires))
+	if err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// ByUser returns all of a user's tokens, including expired ones, newest
+// first.
+func (model *TokenModel) ByUser(userID int) ([]*Token, error) {
+	queryStatement := `
+		SELECT ` + tokenColumns + ` FROM tokens
+		WHERE user_id = ?
+		ORDER BY id DESC
+	`
+	rows, err := model.DB.Query(model.Dialect.rebind(queryStatement), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+	for rows.Next() {
+		token, err := scanRowIntoToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// Delete revokes one of userID's tokens. It returns ErrNoRecord if the token
+// doesn't exist or belongs to someone else.
+func (model *TokenModel) Delete(id, userID int) error {
+	queryStatement := `DELETE FROM tokens WHERE id = ? AND user_id = ?`
+	result, err := model.DB.Exec(model.Dialect.rebind(queryStatement), id, userID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+// Authenticate returns the id of the user a plaintext token belongs to and
+// records that the token was used. Unknown and expired tokens give
+// ErrInvaildCredentials.
+func (model *TokenModel) Authenticate(plaintext string) (int, error) {
+	queryStatement := `
+		SELECT ` + tokenColumns + ` FROM tokens
+		WHERE hash = ?
+	`
+	row := model.DB.QueryRow(model.Dialect.rebind(queryStatement), hashToken(plaintext))
+
+	token, err := scanRowIntoToken(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvaildCredentials
+		}
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	if !token.Expires.IsZero() && !token.Expires.After(now) {
+		return 0, ErrInvaildCredentials
+	}
+
+	queryStatement = `UPDATE tokens SET last_used = ? WHERE id = ?`
+	_, err = model.DB.Exec(model.Dialect.rebind(queryStatement), now, token.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return token.UserID, nil
+}
+
+func scanRowIntoToken(row scanner) (*Token, error) {
+	token := new(Token)
+	var lastUsed, expires sql.NullTime
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Created,
+		&lastUsed,
+		&expires,
+	)
+	if err != nil {
+		return nil, err
+	}
+	token.LastUsed = lastUsed.Time
+	token.Expires = expires.Time
+	return token, nil
+}
+
+// nullTime stores a zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -3,10 +3,8 @@ package models
 import (
 	"database/sql"
 	"errors"
-	"strings"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -19,7 +17,8 @@ type User struct {
 }
 
 type UserModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Dialect Dialect
 }
 
 func (model *UserModel) Insert(name, email, password string) error {
@@ -29,12 +28,11 @@ func (model *UserModel) Insert(name, email, password string) error {
 	}
 	queryStatement := `
 		INSERT INTO users (name, email, hashed_password, created)
-		VALUES (?, ?, ?, UTC_TIMESTAMP())
+		VALUES (?, ?, ?, ?)
 	`
-	_, err = model.DB.Exec(queryStatement, name, email, string(hashedPassword))
+	_, err = model.DB.Exec(model.Dialect.rebind(queryStatement), name, email, string(hashedPassword), time.Now().UTC())
 	if err != nil {
-		var mySQLError *mysql.MySQLError
-		if errors.As(err, &mySQLError) && mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
+		if model.Dialect.isUniqueViolation(err, "users_uc_email", "users.email") {
 			return ErrDuplicateEmail
 		}
 		return err
@@ -51,7 +49,7 @@ func (model *UserModel) Authenticate(email, password string) (int, error) {
 		SELECT id, hashed_password FROM users
 		WHERE email = ?
 	`
-	err := model.DB.QueryRow(queryStatement, email).Scan(&id, &hashedPassword)
+	err := model.DB.QueryRow(model.Dialect.rebind(queryStatement), email).Scan(&id, &hashedPassword)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrInvaildCredentials
@@ -70,6 +68,28 @@ func (model *UserModel) Authenticate(email, password string) (int, error) {
 	return id, nil
 }
 
+func (model *UserModel) Get(id int) (*User, error) {
+	user := new(User)
+
+	queryStatement := `
+		SELECT id, name, email, created FROM users
+		WHERE id = ?
+	`
+	err := model.DB.QueryRow(model.Dialect.rebind(queryStatement), id).Scan(&user.ID, &user.Name, &user.Email, &user.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func (model *UserModel) Exists(id int) (bool, error) {
-	return false, nil
+	var exists bool
+
+	queryStatement := `SELECT EXISTS(SELECT true FROM users WHERE id = ?)`
+	err := model.DB.QueryRow(model.Dialect.rebind(queryStatement), id).Scan(&exists)
+	return exists, err
 }
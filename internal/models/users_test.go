@@ -0,0 +1,51 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/Yusufdot101/snippetbox/internal/models"
+)
+
+func TestUserExists(t *testing.T) {
+	tests := []struct {
+		name  string
+		users func(t *testing.T) models.UserStore
+	}{
+		{"SQLite", func(t *testing.T) models.UserStore {
+			return &models.UserModel{DB: newTestDB(t), Dialect: models.SQLite}
+		}},
+		{"Memory", func(t *testing.T) models.UserStore {
+			return models.NewMemoryModels().Users
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := tt.users(t)
+			if err := users.Insert("Alice", "alice@example.com", "password123"); err != nil {
+				t.Fatal(err)
+			}
+			id, err := users.Authenticate("alice@example.com", "password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, check := range []struct {
+				id   int
+				want bool
+			}{
+				{id, true},
+				{id + 1, false},
+				{0, false},
+			} {
+				exists, err := users.Exists(check.id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exists != check.want {
+					t.Errorf("Exists(%d) = %t; want %t", check.id, exists, check.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"iter"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fragment is a piece of text that either is one of the words searched for
+// or lies between them. Templates put matches in <mark> elements.
+type Fragment struct {
+	Text  string
+	Match bool
+}
+
+// Highlight splits text into fragments, marking the words of query.
+func Highlight(text, query string) []Fragment {
+	words := make(map[string]bool)
+	for _, word := range queryWords(query) {
+		words[word] = true
+	}
+
+	var fragments []Fragment
+	plain := 0
+	for start, end := range wordSpans(text) {
+		if !words[strings.ToLower(text[start:end])] {
+			continue
+		}
+		if plain < start {
+			fragments = append(fragments, Fragment{Text: text[plain:start]})
+		}
+		fragments = append(fragments, Fragment{Text: text[start:end], Match: true})
+		plain = end
+	}
+	if plain < len(text) {
+		fragments = append(fragments, Fragment{Text: text[plain:]})
+	}
+	return fragments
+}
+
+// Excerpt returns about size bytes of text around the first word of query it
+// contains, or from its start if it contains none, with the words of query
+// marked. Runs of white space are collapsed into single spaces, and "…" marks
+// where text was cut.
+func Excerpt(text, query string, size int) []Fragment {
+	text = strings.Join(strings.Fields(text), " ")
+
+	words := make(map[string]bool)
+	for _, word := range queryWords(query) {
+		words[word] = true
+	}
+	first := 0
+	for start, end := range wordSpans(text) {
+		if words[strings.ToLower(text[start:end])] {
+			first = start
+			break
+		}
+	}
+
+	// Leave some context before the match, starting at a word if possible.
+	start := max(0, first-size/4)
+	if start > 0 {
+		if i := strings.IndexByte(text[start:first], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	// End at a word too, unless that would cut off the match.
+	end := min(len(text), start+size)
+	if end < len(text) {
+		if i := strings.LastIndexByte(text[first:end], ' '); i > 0 {
+			end = first + i
+		}
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	excerpt := text[start:end]
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(text) {
+		excerpt += "…"
+	}
+	return Highlight(excerpt, query)
+}
+
+// wordSpans yields the start and end offsets of the words in text, split the
+// same way Words splits them.
+func wordSpans(text string) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		start := -1
+		for i, r := range text {
+			switch {
+			case !isSeparator(r) && start < 0:
+				start = i
+			case isSeparator(r) && start >= 0:
+				if !yield(start, i) {
+					return
+				}
+				start = -1
+			}
+		}
+		if start >= 0 {
+			yield(start, len(text))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text, query string
+		want        []Fragment
+	}{
+		{"nginx config", "nginx", []Fragment{{"nginx", true}, {" config", false}}},
+		{"Nginx.conf for NGINX", "nginx", []Fragment{{"Nginx", true}, {".conf for ", false}, {"NGINX", true}}},
+		{"nginxconfig", "nginx", []Fragment{{"nginxconfig", false}}},
+		{"a b", "c", []Fragment{{"a b", false}}},
+		{"", "nginx", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Highlight(tt.text, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Highlight(%q, %q) = %v; want %v", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+// join puts the fragments back together, with matches in brackets.
+func join(fragments []Fragment) string {
+	var b strings.Builder
+	for _, f := range fragments {
+		if f.Match {
+			b.WriteString("[" + f.Text + "]")
+		} else {
+			b.WriteString(f.Text)
+		}
+	}
+	return b.String()
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("filler ", 50) + "the nginx\n\n   config " + strings.Repeat("filler ", 50)
+
+	tests := []struct {
+		name, text, query, want string
+		size                    int
+	}{
+		{"short text", "listen 80;", "listen", "[listen] 80;", 40},
+		{"white space collapsed", "a\n\n  b", "b", "a [b]", 40},
+		{"no match starts at the start", "one two three four", "five", "one two…", 8},
+		{"around the match", long, "nginx", "…filler the [nginx] config filler filler filler…", 48},
+		{"multibyte text", strings.Repeat("é", 30) + " nginx", "nginx", "…[nginx]", 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := join(Excerpt(tt.text, tt.query, tt.size)); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// Package search finds snippets by the words in their title, language and
+// content, ranked by relevance.
+//
+// The application only depends on the Index interface. Memory, the index it
+// ships with, is kept in the web server's memory and built from the snippet
+// store at startup. It works the same whichever database the snippets are
+// stored in, and unlike MySQL's FULLTEXT indexes it can search snippets whose
+// title and content are encrypted at rest, as it is fed the decrypted text.
+//
+// Text is split into words at anything that isn't a letter or a digit, and
+// words are compared case-insensitively, so "nginx.conf" is found by both
+// "nginx" and "conf". A document matches a query when it contains every word
+// of it. Matches are ranked with BM25, counting words in the title and
+// language several times over, so that a snippet called "nginx config" ranks
+// above one that only mentions nginx in passing.
+package search
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Document is what the index knows about a snippet. Public documents can be
+// found by anyone, the others only by their owner. Expires is the zero time
+// for a document that never expires.
+type Document struct {
+	ID       int
+	UserID   int
+	Public   bool
+	Expires  time.Time
+	Title    string
+	Language string
+	Content  string
+}
+
+// Query asks for the documents matching Text that the user with the id
+// UserID, or 0 for an anonymous visitor, may find. Offset and Limit select a
+// page of the results.
+type Query struct {
+	Text   string
+	UserID int
+	Offset int
+	Limit  int
+}
+
+// Hit is a document matching a query, with its relevance score.
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+// Results are a page of the hits for a query, the most relevant first, and
+// the total number of hits across all pages.
+type Results struct {
+	Hits  []Hit
+	Total int
+}
+
+// Index holds the documents that can be searched.
+type Index interface {
+	// Add adds the document, replacing any with the same ID.
+	Add(doc Document) error
+	// Remove removes the document with the given ID, if there is one.
+	Remove(id int) error
+	// RemoveExpired removes the documents that expired before now.
+	RemoveExpired(now time.Time) error
+	Search(q Query) (Results, error)
+}
+
+const (
+	// titleWeight is how many times a word in the title or the language
+	// counts compared to one in the content.
+	titleWeight = 3
+	// maxWordLength is the longest word indexed. Longer ones are more likely
+	// to be base64 or minified code than anything someone would search for.
+	maxWordLength = 64
+	// maxQueryWords is how many words of a query are used.
+	maxQueryWords = 16
+
+	// BM25 parameters, set to the usual values.
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Words splits text into the lower case words the index is made of.
+func Words(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), isSeparator)
+	return slices.DeleteFunc(words, func(word string) bool {
+		return len(word) > maxWordLength
+	})
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// queryWords returns the distinct words of a query.
+func queryWords(text string) []string {
+	words := Words(text)
+	slices.Sort(words)
+	words = slices.Compact(words)
+	return words[:min(len(words), maxQueryWords)]
+}
+
+// entry is an indexed document without its text, which isn't needed once its
+// words have been counted.
+type entry struct {
+	userID  int
+	public  bool
+	expires time.Time
+	// frequencies counts the document's words, weighted by where they were
+	// found, and length is their sum.
+	frequencies map[string]int
+	length      int
+}
+
+func (e *entry) visibleTo(userID int) bool {
+	return e.public || (userID != 0 && e.userID == userID)
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !e.expires.After(now)
+}
+
+// Memory is an inverted index kept in memory. It is safe for concurrent use.
+type Memory struct {
+	mu      sync.RWMutex
+	entries map[int]*entry
+	// postings lists the IDs of the documents containing each word.
+	postings map[string]map[int]struct{}
+	// totalLength is the sum of the lengths of all entries.
+	totalLength int
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		entries:  make(map[int]*entry),
+		postings: make(map[string]map[int]struct{}),
+	}
+}
+
+func (m *Memory) Add(doc Document) error {
+	e := &entry{
+		userID:      doc.UserID,
+		public:      doc.Public,
+		expires:     doc.Expires,
+		frequencies: make(map[string]int),
+	}
+	for _, word := range append(Words(doc.Title), Words(doc.Language)...) {
+		e.frequencies[word] += titleWeight
+		e.length += titleWeight
+	}
+	for _, word := range Words(doc.Content) {
+		e.frequencies[word]++
+		e.length++
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(doc.ID)
+	m.entries[doc.ID] = e
+	m.totalLength += e.length
+	for word := range e.frequencies {
+		ids, ok := m.postings[word]
+		if !ok {
+			ids = make(map[int]struct{})
+			m.postings[word] = ids
+		}
+		ids[doc.ID] = struct{}{}
+	}
+	return nil
+}
+
+func (m *Memory) Remove(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(id)
+	return nil
+}
+
+func (m *Memory) RemoveExpired(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, e := range m.entries {
+		if e.expired(now) {
+			m.remove(id)
+		}
+	}
+	return nil
+}
+
+// remove drops the entry with the given id. The caller must hold the write
+// lock.
+func (m *Memory) remove(id int) {
+	e, ok := m.entries[id]
+	if !ok {
+		return
+	}
+
+	for word := range e.frequencies {
+		delete(m.postings[word], id)
+		if len(m.postings[word]) == 0 {
+			delete(m.postings, word)
+		}
+	}
+	m.totalLength -= e.length
+	delete(m.entries, id)
+}
+
+func (m *Memory) Search(q Query) (Results, error) {
+	words := queryWords(q.Text)
+	if len(words) == 0 {
+		return Results{}, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Only documents containing the rarest word can contain them all, so
+	// those are the only ones that need to be looked at.
+	rarest := words[0]
+	for _, word := range words[1:] {
+		if len(m.postings[word]) < len(m.postings[rarest]) {
+			rarest = word
+		}
+	}
+
+	now := time.Now()
+	avgLength := float64(m.totalLength) / float64(max(len(m.entries), 1))
+
+	var hits []Hit
+candidates:
+	for id := range m.postings[rarest] {
+		e := m.entries[id]
+		if !e.visibleTo(q.UserID) || e.expired(now) {
+			continue
+		}
+
+		var score float64
+		for _, word := range words {
+			tf := float64(e.frequencies[word])
+			if tf == 0 {
+				continue candidates
+			}
+			score += m.idf(word) * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(e.length)/avgLength))
+		}
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	// The newest document comes first among those that score the same.
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return b.ID - a.ID
+	})
+
+	results := Results{Total: len(hits)}
+	if q.Offset < len(hits) {
+		hits = hits[q.Offset:]
+		results.Hits = hits[:min(q.Limit, len(hits))]
+	}
+	return results, nil
+}
+
+// idf is the inverse document frequency of word: the fewer documents contain
+// it, the more a match on it counts.
+func (m *Memory) idf(word string) float64 {
+	n, df := float64(len(m.entries)), float64(len(m.postings[word]))
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
@@ -0,0 +1,187 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"nginx.conf", []string{"nginx", "conf"}},
+		{"proxy_pass http://127.0.0.1:8080;", []string{"proxy", "pass", "http", "127", "0", "0", "1", "8080"}},
+		{"Grüße aus Zürich", []string{"grüße", "aus", "zürich"}},
+		{"short " + strings.Repeat("x", maxWordLength+1), []string{"short"}},
+	}
+
+	for _, tt := range tests {
+		if got := Words(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Words(%q) = %q; want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// ids returns the IDs of the hits, in order.
+func ids(results Results) []int {
+	var ids []int
+	for _, hit := range results.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+// search runs a query for an anonymous visitor and returns the IDs of the
+// hits.
+func search(t *testing.T, index Index, text string) []int {
+	t.Helper()
+	results, err := index.Search(Query{Text: text, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids(results)
+}
+
+func TestMemoryRanking(t *testing.T) {
+	index := NewMemory()
+	docs := []Document{
+		{ID: 1, Public: true, Title: "deploy notes", Content: "restart nginx after the deploy, then check the logs and the queue"},
+		{ID: 2, Public: true, Title: "nginx config", Language: "nginx", Content: "server { listen 80; }"},
+		{ID: 3, Public: true, Title: "shopping list", Content: "milk, eggs, bread"},
+		{ID: 4, Public: true, Title: "proxy", Content: "nginx nginx nginx reverse proxy"},
+	}
+	for _, doc := range docs {
+		index.Add(doc)
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// a match in the title counts for more than in the content, and
+		// repeated matches for more than a single one in a longer text
+		{"nginx", []int{2, 4, 1}},
+		{"NGINX", []int{2, 4, 1}},
+		// every word has to match
+		{"nginx deploy", []int{1}},
+		{"nginx milk", nil},
+		{"listen", []int{2}},
+		{"", nil},
+		{"...", nil},
+	}
+
+	for _, tt := range tests {
+		if got := search(t, index, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("search for %q = %v; want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryVisibilityAndExpiry(t *testing.T) {
+	index := NewMemory()
+	now := time.Now()
+	docs := []Document{
+		{ID: 1, UserID: 1, Public: true, Title: "note"},
+		{ID: 2, UserID: 1, Title: "note"},
+		{ID: 3, UserID: 2, Title: "note"},
+		{ID: 4, UserID: 1, Public: true, Title: "note", Expires: now.Add(-time.Minute)},
+		{ID: 5, UserID: 1, Public: true, Title: "note", Expires: now.Add(time.Hour)},
+	}
+	for _, doc := range docs {
+		index.Add(doc)
+	}
+
+	tests := []struct {
+		userID int
+		want   []int
+	}{
+		{0, []int{5, 1}},
+		{1, []int{5, 2, 1}},
+		{2, []int{5, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		results, err := index.Search(Query{Text: "note", UserID: tt.userID, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(results); !slices.Equal(got, tt.want) {
+			t.Errorf("user %d found %v; want %v", tt.userID, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryPagination(t *testing.T) {
+	index := NewMemory()
+	for id := 1; id <= 25; id++ {
+		index.Add(Document{ID: id, Public: true, Title: "same"})
+	}
+
+	tests := []struct {
+		offset, limit int
+		want          []int
+	}{
+		// equal scores put the newest first
+		{0, 3, []int{25, 24, 23}},
+		{10, 3, []int{15, 14, 13}},
+		{23, 10, []int{2, 1}},
+		{25, 10, nil},
+		{40, 10, nil},
+	}
+
+	for _, tt := range tests {
+		results, err := index.Search(Query{Text: "same", Offset: tt.offset, Limit: tt.limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results.Total != 25 {
+			t.Errorf("offset %d: got total %d; want 25", tt.offset, results.Total)
+		}
+		if got := ids(results); !slices.Equal(got, tt.want) {
+			t.Errorf("offset %d, limit %d: got %v; want %v", tt.offset, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryUpdatesAndRemovals(t *testing.T) {
+	index := NewMemory()
+	now := time.Now()
+	index.Add(Document{ID: 1, Public: true, Title: "nginx config"})
+	index.Add(Document{ID: 2, Public: true, Title: "nginx notes", Expires: now.Add(time.Hour)})
+	index.Add(Document{ID: 3, Public: true, Title: "nginx logs"})
+
+	// adding a document again replaces what was indexed for it
+	index.Add(Document{ID: 1, Public: true, Title: "apache config"})
+	if got := search(t, index, "nginx"); !slices.Equal(got, []int{3, 2}) {
+		t.Errorf("after replacing 1, nginx found %v; want [3 2]", got)
+	}
+	if got := search(t, index, "apache"); !slices.Equal(got, []int{1}) {
+		t.Errorf("after replacing 1, apache found %v; want [1]", got)
+	}
+
+	index.Remove(3)
+	index.Remove(42)
+	if got := search(t, index, "nginx"); !slices.Equal(got, []int{2}) {
+		t.Errorf("after removing 3, nginx found %v; want [2]", got)
+	}
+
+	index.RemoveExpired(now.Add(2 * time.Hour))
+	if got := search(t, index, "nginx"); got != nil {
+		t.Errorf("after 2 expired, nginx found %v; want nothing", got)
+	}
+	if got := search(t, index, "apache"); !slices.Equal(got, []int{1}) {
+		t.Errorf("a document that never expires was removed with the expired ones")
+	}
+
+	// removing drops the words of a document from the index altogether
+	index.Remove(1)
+	if len(index.entries) != 0 || len(index.postings) != 0 || index.totalLength != 0 {
+		t.Errorf("empty index still holds %d entries, %d words and a length of %d",
+			len(index.entries), len(index.postings), index.totalLength)
+	}
+}
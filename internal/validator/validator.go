@@ -3,7 +3,6 @@ package validator
 import (
 	"regexp"
 	"slices"
-	"strconv"
 	"strings"
 	"unicode/utf8"
 )
@@ -47,10 +46,6 @@ func MaxChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) <= n
 }
 
-func PremittedInt(value int, permittedValues ...string) bool {
-	return slices.Contains(permittedValues, strconv.Itoa(value))
-}
-
 func MinChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) >= 8
 }
@@ -58,3 +53,7 @@ func MinChars(value string, n int) bool {
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
+
+func PermittedValue(value string, permittedValues ...string) bool {
+	return slices.Contains(permittedValues, value)
+}
@@ -0,0 +1,129 @@
+"use strict";
+
+// Snippets can be encrypted in the browser before they are sent, so that the
+// server only ever stores ciphertext. The key is kept in the fragment of the
+// snippet's link, the part after "#", which browsers never send to servers.
+//
+// The ciphertext is "v1.<iv>.<data>", both parts base64url encoded, where
+// data is the UTF-8 content encrypted with AES-256-GCM. cmd/web checks for
+// this shape and cmd/sbx reads and writes it too.
+
+const ciphertextVersion = "v1";
+
+function toBase64URL(bytes) {
+    let binary = "";
+    for (const b of new Uint8Array(bytes)) {
+        binary += String.fromCharCode(b);
+    }
+    return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+function fromBase64URL(text) {
+    const base64 = text.replace(/-/g, "+").replace(/_/g, "/");
+    const binary = atob(base64 + "=".repeat((4 - (base64.length % 4)) % 4));
+    return Uint8Array.from(binary, (c) => c.charCodeAt(0));
+}
+
+function newKey() {
+    return toBase64URL(crypto.getRandomValues(new Uint8Array(32)));
+}
+
+function keyFromLocation() {
+    return location.hash.slice(1);
+}
+
+function importKey(encodedKey, usage) {
+    return crypto.subtle.importKey("raw", fromBase64URL(encodedKey), "AES-GCM", false, [usage]);
+}
+
+async function encrypt(plaintext, encodedKey) {
+    const key = await importKey(encodedKey, "encrypt");
+    const iv = crypto.getRandomValues(new Uint8Array(12));
+    const data = await crypto.subtle.encrypt({ name: "AES-GCM", iv }, key, new TextEncoder().encode(plaintext));
+    return [ciphertextVersion, toBase64URL(iv), toBase64URL(data)].join(".");
+}
+
+async function decrypt(ciphertext, encodedKey) {
+    const [version, iv, data] = ciphertext.split(".");
+    if (version !== ciphertextVersion || !iv || !data) {
+        throw new Error("unknown ciphertext format");
+    }
+    const key = await importKey(encodedKey, "decrypt");
+    const plaintext = await crypto.subtle.decrypt({ name: "AES-GCM", iv: fromBase64URL(iv) }, key, fromBase64URL(data));
+    return new TextDecoder().decode(plaintext);
+}
+
+// showEncrypted replaces the placeholder of an encrypted snippet with its
+// content, decrypted with the key from the link.
+async function showEncrypted(pre) {
+    const code = pre.querySelector("code");
+    const key = keyFromLocation();
+    if (!key) {
+        code.textContent = "This snippet is encrypted and the link has no key. Ask whoever shared it for the whole link, including the part after #.";
+        return;
+    }
+
+    try {
+        code.textContent = await decrypt(pre.dataset.ciphertext, key);
+    } catch (err) {
+        code.textContent = "This snippet couldn't be decrypted. The key at the end of the link is wrong or incomplete.";
+    }
+}
+
+// setUpSnippetForm encrypts the content of a create or edit form before it is
+// submitted, if asked to. The key is added to the form's action as a
+// fragment, which browsers carry over to the page they are redirected to.
+function setUpSnippetForm(form) {
+    const checkbox = form.querySelector('input[name="encrypted"]');
+    const content = form.querySelector('textarea[name="content"]');
+    let key = keyFromLocation();
+
+    // editing an encrypted snippet, or fixing a rejected form, starts from
+    // the plaintext
+    if (checkbox.checked && content.value) {
+        decrypt(content.value, key)
+            .then((plaintext) => {
+                content.value = plaintext;
+            })
+            .catch(() => {
+                content.value = "";
+                content.placeholder = "This snippet is encrypted and the link of this page has no key for it. Open it from the snippet's full link, or write new content.";
+            });
+    }
+
+    form.addEventListener("submit", async (event) => {
+        if (!checkbox.checked || !content.value.trim()) {
+            return;
+        }
+        event.preventDefault();
+
+        if (!key) {
+            key = newKey();
+        }
+        content.value = await encrypt(content.value, key);
+        form.action = form.action.split("#")[0] + "#" + key;
+
+        // submit() doesn't fire the submit event again
+        form.submit();
+    });
+}
+
+// Links and forms marked data-keep-key lead to pages that need the key too.
+for (const element of document.querySelectorAll("[data-keep-key]")) {
+    if (!location.hash) {
+        break;
+    }
+    if (element instanceof HTMLFormElement) {
+        element.action += location.hash;
+    } else {
+        element.href += location.hash;
+    }
+}
+
+for (const pre of document.querySelectorAll("pre[data-ciphertext]")) {
+    showEncrypted(pre);
+}
+
+for (const checkbox of document.querySelectorAll('input[name="encrypted"]')) {
+    setUpSnippetForm(checkbox.form);
+}